@@ -0,0 +1,57 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+// Number is a constraint for numeric key types, which can be split into windows by AggregateWindows.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// AggregateWindows splits interval [from; to) into consecutive windows of a given width, starting at from,
+// and returns the results of agg for each window in order, including empty ones. agg is called with
+// the start of a window and its pairs; the slice is reused between calls and must not be retained.
+// Leaves are walked once. Returns nil if width is not positive or from is not less than to.
+func AggregateWindows[K Number, V any, R any](t *BPTree[K, V], from, to, width K, agg func(start K, entries []KeyValue[K, V]) R) []R {
+	if !(width > 0) || !(from < to) {
+		return nil
+	}
+	var results []R
+	var entries []KeyValue[K, V]
+	integer := K(1)/K(2) == 0
+	i := t.NewIterator(&from, &to)
+	kv, ok := i.Next()
+	for n, start := 1, from; ; n++ {
+		end := start + width
+		if !integer {
+			// float bounds are computed by multiplication, so windows do not drift
+			end = from + K(n)*width
+		}
+		if !(start < end) || to < end {
+			// overflow or lost float precision, or the last window, which is cut at to
+			end = to
+		}
+		entries = entries[:0]
+		for ; ok && kv.Key < end; kv, ok = i.Next() {
+			entries = append(entries, kv)
+		}
+		results = append(results, agg(start, entries))
+		if !(end < to) {
+			return results
+		}
+		start = end
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAggregateWindows(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	for _, k := range genKeys(numKeys) {
+		t.Append(k, k)
+		t.Append(k, -k)
+	}
+	count := func(start int, entries []KeyValue[int, int]) [2]int {
+		return [2]int{start, len(entries)}
+	}
+	windows := AggregateWindows(t, 95, 130, 10, count)
+	needed := [][2]int{{95, 20}, {105, 20}, {115, 20}, {125, 10}}
+	if len(windows) != len(needed) {
+		failf(T, t, "windows: %v, needed %v", windows, needed)
+	}
+	for i := range needed {
+		if windows[i] != needed[i] {
+			failf(T, t, "windows: %v, needed %v", windows, needed)
+		}
+	}
+	sum := AggregateWindows(t, -100, numKeys+100, 100, func(start int, entries []KeyValue[int, int]) int {
+		var s int
+		for _, kv := range entries {
+			s += kv.Key
+		}
+		return s
+	})
+	if len(sum) != numKeys/100+2 || sum[0] != 0 || sum[1] != 2*(0+99)*100/2 || sum[len(sum)-1] != 0 {
+		failf(T, t, "sums: %v", sum)
+	}
+	if AggregateWindows(t, 10, 10, 1, count) != nil || AggregateWindows(t, 0, 10, 0, count) != nil {
+		fail(T, t, "windows of empty interval or zero width")
+	}
+	if w := AggregateWindows(t, math.MaxInt-15, math.MaxInt, 10, count); len(w) != 2 || w[1][0] != math.MaxInt-5 {
+		failf(T, t, "windows near max int: %v", w)
+	}
+
+	ft := NewBPTree[float64, int](bmax)
+	for i := 0; i < 100; i++ {
+		ft.Insert(float64(i)*0.1, i)
+	}
+	fw := AggregateWindows(ft, 0, 10, 0.1, func(start float64, entries []KeyValue[float64, int]) int {
+		return len(entries)
+	})
+	if len(fw) != 100 {
+		T.Fatalf("%d float windows", len(fw))
+	}
+	for i, n := range fw {
+		if n != 1 {
+			T.Fatalf("float window %d has %d entries", i, n)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// Methods in this file mirror the callback iteration API of github.com/google/btree.
+// Pivots are keys, and items passed to the callback are key-value pairs. Multiple values
+// of the same key are passed one by one, in reverse order for descending methods.
+
+// ItemIterator is called for each visited key-value pair. Returning false stops the iteration.
+type ItemIterator[K Key, V any] func(item KeyValue[K, V]) bool
+
+// Ascend calls the iterator for every pair in the tree in ascending order.
+func (t *BPTree[K, V]) Ascend(iterator ItemIterator[K, V]) {
+	t.ascend(nil, nil, iterator)
+}
+
+// AscendRange calls the iterator for every pair with key from interval [greaterOrEqual; lessThan) in ascending order.
+func (t *BPTree[K, V]) AscendRange(greaterOrEqual, lessThan K, iterator ItemIterator[K, V]) {
+	t.ascend(&greaterOrEqual, &lessThan, iterator)
+}
+
+// AscendLessThan calls the iterator for every pair with key less than pivot in ascending order.
+func (t *BPTree[K, V]) AscendLessThan(pivot K, iterator ItemIterator[K, V]) {
+	t.ascend(nil, &pivot, iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator for every pair with key greater or equal to pivot in ascending order.
+func (t *BPTree[K, V]) AscendGreaterOrEqual(pivot K, iterator ItemIterator[K, V]) {
+	t.ascend(&pivot, nil, iterator)
+}
+
+// Descend calls the iterator for every pair in the tree in descending order.
+func (t *BPTree[K, V]) Descend(iterator ItemIterator[K, V]) {
+	t.descend(nil, nil, iterator)
+}
+
+// DescendRange calls the iterator for every pair with key from interval (greaterThan; lessOrEqual] in descending order.
+func (t *BPTree[K, V]) DescendRange(lessOrEqual, greaterThan K, iterator ItemIterator[K, V]) {
+	t.descend(&lessOrEqual, &greaterThan, iterator)
+}
+
+// DescendLessOrEqual calls the iterator for every pair with key less or equal to pivot in descending order.
+func (t *BPTree[K, V]) DescendLessOrEqual(pivot K, iterator ItemIterator[K, V]) {
+	t.descend(&pivot, nil, iterator)
+}
+
+// DescendGreaterThan calls the iterator for every pair with key greater than pivot in descending order.
+func (t *BPTree[K, V]) DescendGreaterThan(pivot K, iterator ItemIterator[K, V]) {
+	t.descend(nil, &pivot, iterator)
+}
+
+func (t *BPTree[K, V]) ascend(from, to *K, iterator ItemIterator[K, V]) {
+	t.withLabels("ascend", func() {
+		i := t.Iterator(from, to)
+		for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+			if !iterator(kv) {
+				return
+			}
+		}
+	})
+}
+
+func (t *BPTree[K, V]) descend(lessOrEqual, greaterThan *K, iterator ItemIterator[K, V]) {
+	t.withLabels("descend", func() {
+		t.descendCursor(lessOrEqual, greaterThan, iterator)
+	})
+}
+
+func (t *BPTree[K, V]) descendCursor(lessOrEqual, greaterThan *K, iterator ItemIterator[K, V]) {
+	c := t.Cursor()
+	var k K
+	var v V
+	var ok bool
+	if lessOrEqual == nil {
+		k, v, ok = c.Last()
+	} else {
+		k, v, ok = c.seekLE(*lessOrEqual)
+	}
+	for ; ok; k, v, ok = c.Prev() {
+		if greaterThan != nil && !cmp.Less(*greaterThan, k) {
+			return
+		}
+		if !iterator(KeyValue[K, V]{Key: k, Value: v}) {
+			return
+		}
+	}
+}
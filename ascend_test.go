@@ -0,0 +1,95 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func collectItems[K Key, V any](f func(ItemIterator[K, V])) []KeyValue[K, V] {
+	var items []KeyValue[K, V]
+	f(func(item KeyValue[K, V]) bool {
+		items = append(items, item)
+		return true
+	})
+	return items
+}
+
+func compareItems[K Key, V any](T *testing.T, t *BPTree[K, V], name string, items, needed []KeyValue[K, V]) {
+	if len(items) != len(needed) {
+		failf(T, t, "%s: len(items)(%d) != len(needed)(%d)", name, len(items), len(needed))
+	}
+	for i := range items {
+		if items[i] != needed[i] {
+			failf(T, t, "%s: items[%d](%v) != needed[%d](%v)", name, i, items[i], i, needed[i])
+		}
+	}
+}
+
+func reverseItems[K Key, V any](items []KeyValue[K, V]) []KeyValue[K, V] {
+	var r []KeyValue[K, V]
+	for i := len(items) - 1; i >= 0; i-- {
+		r = append(r, items[i])
+	}
+	return r
+}
+
+func TestAscendDescend(T *testing.T) {
+	b, n := bmax, numRangeTestKeys
+	t := NewBPTree[int, int](b)
+	keys, values := makeAppendKeysValues(n)
+	for i, k := range keys {
+		t.Append(k*2, values[i])
+	}
+	compareItems(T, t, "Ascend", collectItems(t.Ascend), t.Entries())
+	compareItems(T, t, "Descend", collectItems(t.Descend), reverseItems(t.Entries()))
+	for from := -1; from <= n*2; from++ {
+		for to := -1; to <= n*2; to++ {
+			f, l := from, to
+			compareItems(T, t, "AscendRange", collectItems(func(it ItemIterator[int, int]) {
+				t.AscendRange(f, l, it)
+			}), t.Range(&f, &l))
+			ge, gt := to+1, from+1
+			var needed []KeyValue[int, int]
+			if gt < ge {
+				needed = reverseItems(t.Range(&gt, &ge))
+			}
+			compareItems(T, t, "DescendRange", collectItems(func(it ItemIterator[int, int]) {
+				t.DescendRange(to, from, it)
+			}), needed)
+		}
+		p, p1 := from, from+1
+		compareItems(T, t, "AscendLessThan", collectItems(func(it ItemIterator[int, int]) {
+			t.AscendLessThan(p, it)
+		}), t.Range(nil, &p))
+		compareItems(T, t, "AscendGreaterOrEqual", collectItems(func(it ItemIterator[int, int]) {
+			t.AscendGreaterOrEqual(p, it)
+		}), t.Range(&p, nil))
+		compareItems(T, t, "DescendLessOrEqual", collectItems(func(it ItemIterator[int, int]) {
+			t.DescendLessOrEqual(p, it)
+		}), reverseItems(t.Range(nil, &p1)))
+		compareItems(T, t, "DescendGreaterThan", collectItems(func(it ItemIterator[int, int]) {
+			t.DescendGreaterThan(p, it)
+		}), reverseItems(t.Range(&p1, nil)))
+	}
+	var visited int
+	t.Descend(func(KeyValue[int, int]) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		failf(T, t, "Descend not stopped: visited %d", visited)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+)
+
+// ErrConflict is returned by Batch.CommitIf if the tree has been modified since the expected version.
+var ErrConflict = errors.New("tree has been modified")
+
+// Batch accumulates mutations of a tree and applies them on Flush in key order. Multiple mutations
+// of the same key are coalesced, so each key is written once per Flush. Reads through the batch
+// see pending mutations layered over the tree.
+type Batch[K Key, V any] struct {
+	t       *BPTree[K, V]
+	ops     map[K]*batchOp[V]
+	nan     *batchOp[V] // mutation of a NaN key, which can not be found in a map
+	nanKey  K
+	version uint64
+}
+
+// batchOp is a coalesced mutation of a key: existing values are removed if reset is set,
+// then vals are appended.
+type batchOp[V any] struct {
+	reset bool
+	vals  []V
+}
+
+// Mutation is a coalesced mutation of a key: existing values are removed if Reset is set,
+// then Values are appended.
+type Mutation[K Key, V any] struct {
+	Key    K
+	Reset  bool
+	Values []V
+}
+
+// NewBatch returns a new empty Batch for the tree.
+func (t *BPTree[K, V]) NewBatch() *Batch[K, V] {
+	return &Batch[K, V]{
+		t:       t,
+		ops:     make(map[K]*batchOp[V]),
+		version: t.version,
+	}
+}
+
+// Version returns a version of the tree the batch was created or last flushed at.
+// Tree version changes on every modification of the tree.
+func (b *Batch[K, V]) Version() uint64 {
+	return b.version
+}
+
+// Len returns a number of keys with pending mutations.
+func (b *Batch[K, V]) Len() int {
+	if b.nan != nil {
+		return len(b.ops) + 1
+	}
+	return len(b.ops)
+}
+
+// Insert stages replacing all values of a key with val, like BPTree.Insert.
+func (b *Batch[K, V]) Insert(key K, val V) {
+	b.setOp(key, &batchOp[V]{reset: true, vals: []V{val}})
+}
+
+// Append stages appending val to values of a key, like BPTree.Append.
+func (b *Batch[K, V]) Append(key K, val V) {
+	if op, ok := b.op(key); ok {
+		op.vals = append(op.vals, val)
+	} else {
+		b.setOp(key, &batchOp[V]{vals: []V{val}})
+	}
+}
+
+// Delete stages removing a key with all its values, like BPTree.DeleteAll.
+func (b *Batch[K, V]) Delete(key K) {
+	b.setOp(key, &batchOp[V]{reset: true})
+}
+
+// Discard drops all pending mutations.
+func (b *Batch[K, V]) Discard() {
+	clear(b.ops)
+	b.nan = nil
+}
+
+// op returns a pending mutation of a key.
+func (b *Batch[K, V]) op(key K) (*batchOp[V], bool) {
+	if key != key {
+		return b.nan, b.nan != nil
+	}
+	op, ok := b.ops[key]
+	return op, ok
+}
+
+// setOp sets a pending mutation of a key. All NaN keys share one mutation, as they are equal for the tree.
+func (b *Batch[K, V]) setOp(key K, op *batchOp[V]) {
+	if key != key {
+		b.nanKey, b.nan = key, op
+		return
+	}
+	b.ops[key] = op
+}
+
+// keys returns keys with pending mutations in ascending order.
+func (b *Batch[K, V]) keys() []K {
+	keys := make([]K, 0, b.Len())
+	for k := range b.ops {
+		keys = append(keys, k)
+	}
+	if b.nan != nil {
+		keys = append(keys, b.nanKey)
+	}
+	slices.SortFunc(keys, cmp.Compare[K])
+	return keys
+}
+
+// Flush applies all pending mutations to the tree in key order and empties the batch.
+func (b *Batch[K, V]) Flush() {
+	b.t.applyMutations(b.Mutations())
+	b.Discard()
+	b.version = b.t.version
+}
+
+// Mutations returns pending mutations in key order.
+func (b *Batch[K, V]) Mutations() []Mutation[K, V] {
+	keys := b.keys()
+	ms := make([]Mutation[K, V], len(keys))
+	for i, k := range keys {
+		op, _ := b.op(k)
+		ms[i] = Mutation[K, V]{Key: k, Reset: op.reset, Values: op.vals}
+	}
+	return ms
+}
+
+// CommitIf flushes the batch like Flush if the tree version is equal to expected,
+// otherwise returns ErrConflict and keeps pending mutations. Passing Version of the batch
+// gives optimistic concurrency: commit fails if anything has modified the tree since the batch
+// was created or last flushed, e.g. after decisions were made on reads through the batch.
+func (b *Batch[K, V]) CommitIf(expected uint64) error {
+	if b.t.version != expected {
+		return ErrConflict
+	}
+	b.Flush()
+	return nil
+}
+
+// Find is like BPTree.Find, but takes pending mutations into account.
+func (b *Batch[K, V]) Find(key K) (V, bool) {
+	op, ok := b.op(key)
+	if !ok || !op.reset {
+		if v, ok := b.t.Find(key); ok {
+			return v, true
+		}
+	}
+	if ok && len(op.vals) != 0 {
+		return op.vals[0], true
+	}
+	var zero V
+	return zero, false
+}
+
+// FindAll is like BPTree.FindAll, but takes pending mutations into account. Pending values are ordered
+// as if they were appended to the tree, according to SetFindAllOrder.
+func (b *Batch[K, V]) FindAll(key K) ([]V, bool) {
+	op, ok := b.op(key)
+	if !ok {
+		return b.t.FindAll(key)
+	}
+	var vals []V
+	if !op.reset {
+		b.t.FindAllIndexed(key, func(_ int, v V) bool {
+			vals = append(vals, v)
+			return true
+		})
+	}
+	vals = append(vals, op.vals...)
+	if b.t.findAllOrder == NewestFirst {
+		slices.Reverse(vals)
+	}
+	return vals, len(vals) != 0
+}
+
+// Range is like BPTree.Range, but takes pending mutations into account.
+func (b *Batch[K, V]) Range(from, to *K) []KeyValue[K, V] {
+	var result []KeyValue[K, V]
+	i := b.t.Iterator(from, to)
+	kv, ok := i.Next()
+	for _, k := range b.keys() {
+		if from != nil && cmp.Less(k, *from) || to != nil && !cmp.Less(k, *to) {
+			continue
+		}
+		for ; ok && cmp.Less(kv.Key, k); kv, ok = i.Next() {
+			result = append(result, kv)
+		}
+		op, _ := b.op(k)
+		for ; ok && cmp.Compare(kv.Key, k) == 0; kv, ok = i.Next() {
+			if !op.reset {
+				result = append(result, kv)
+			}
+		}
+		for _, v := range op.vals {
+			result = append(result, KeyValue[K, V]{Key: k, Value: v})
+		}
+	}
+	for ; ok; kv, ok = i.Next() {
+		result = append(result, kv)
+	}
+	return result
+}
+
+// applyMutations applies mutations in their order. Mutations of ascending keys falling into the same leaf
+// are applied in place after one descent, as long as they neither remove keys nor overflow the leaf.
+// Other mutations are applied one by one.
+func (t *BPTree[K, V]) applyMutations(ms []Mutation[K, V]) {
+	for len(ms) != 0 {
+		n := t.applyRun(ms)
+		if n == 0 {
+			t.applyMutation(ms[0])
+			n = 1
+		}
+		ms = ms[n:]
+	}
+}
+
+// applyMutation applies a mutation with a replacing insert of its first value if it is reset,
+// and appends of the rest.
+func (t *BPTree[K, V]) applyMutation(m Mutation[K, V]) {
+	vals := m.Values
+	if m.Reset {
+		if len(vals) == 0 {
+			t.DeleteAll(m.Key)
+			return
+		}
+		t.insert(m.Key, vals[0], true)
+		vals = vals[1:]
+	}
+	for _, v := range vals {
+		t.insert(m.Key, v, false)
+	}
+}
+
+// applyRun descends to the leaf of the first mutation, applies in place the longest run of mutations
+// of ascending keys falling into the leaf, and returns the number of applied mutations.
+func (t *BPTree[K, V]) applyRun(ms []Mutation[K, V]) int {
+	// nodes on the way to the leaf, whose counts are updated after the run
+	path := make([]*node[K, V], 0, 16)
+	var hi *K // the minimal key of the next leaf, if any
+	n := t.root
+	for n.isInternal() {
+		path = append(path, n)
+		i := n.childIndex(ms[0].Key)
+		if i < len(n.keys) {
+			hi = &n.keys[i]
+		}
+		n = n.children[i]
+	}
+	var applied, added int
+	var changed bool
+	for j, m := range ms {
+		if j > 0 && (!cmp.Less(ms[j-1].Key, m.Key) || hi != nil && !cmp.Less(m.Key, *hi)) {
+			break
+		}
+		a, c, ok := t.applyToLeaf(n, m)
+		if !ok {
+			break
+		}
+		applied++
+		added += a
+		changed = changed || c
+	}
+	if changed {
+		for _, p := range path {
+			p.count += added
+		}
+		t.size += added
+		t.version++
+	}
+	return applied
+}
+
+// applyToLeaf applies a mutation to the leaf covering its key and returns the change of number of pairs
+// and whether the leaf is changed, or false if the mutation can not be applied without rebalancing.
+func (t *BPTree[K, V]) applyToLeaf(n *node[K, V], m Mutation[K, V]) (added int, changed, ok bool) {
+	reset, vals := m.Reset, m.Values
+	if t.noDuplicates && len(vals) != 0 {
+		reset, vals = true, vals[len(vals)-1:]
+	}
+	pos, found := n.search(m.Key)
+	var old any
+	live := false
+	if found {
+		old = n.values[pos]
+		_, dead := old.(tombstone)
+		live = !dead
+	}
+	switch {
+	case len(vals) == 0:
+		// removing values of a present key may need rebalancing, and other mutations change nothing
+		return 0, false, !(reset && live)
+	case !found && len(n.keys) == cap(n.keys):
+		return 0, false, false
+	}
+	if t.recorder != nil {
+		for i, v := range vals {
+			op := recordAppend
+			if reset && i == 0 {
+				op = recordInsert
+			}
+			t.recorder.record(logRecord[K, V]{Op: op, Key: m.Key, Value: v})
+		}
+	}
+	if t.memCostFn != nil {
+		if reset && live {
+			t.memCost -= t.valuesCost(m.Key, old)
+		}
+		for _, v := range vals {
+			t.memCost += t.memCostFn(m.Key, v)
+		}
+	}
+	added = len(vals)
+	var slot any
+	switch {
+	case live && !reset:
+		c, ok := old.(collision[V])
+		if !ok {
+			t.counters.CollisionAllocs++
+			c = collision[V]{old.(V)}
+		}
+		slot = append(c, vals...)
+	case len(vals) == 1:
+		slot = vals[0]
+	default:
+		slot = collision[V](slices.Clone(vals))
+	}
+	inserts := len(vals)
+	if live && reset {
+		added -= valueCount[V](old)
+		inserts--
+	}
+	t.counters.Inserts += uint64(inserts)
+	switch {
+	case live:
+		n.values[pos] = slot
+	case found:
+		n.values[pos] = slot
+		t.tombstones--
+	default:
+		n.keys = n.keys[:len(n.keys)+1]
+		n.values = n.values[:len(n.values)+1]
+		copy(n.keys[pos+1:], n.keys[pos:len(n.keys)-1])
+		copy(n.values[pos+1:], n.values[pos:len(n.values)-1])
+		n.keys[pos] = m.Key
+		n.values[pos] = slot
+	}
+	n.count += added
+	return added, true, true
+}
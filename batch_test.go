@@ -0,0 +1,224 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestBatch(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	b := t.NewBatch()
+	for round := 0; round < 10; round++ {
+		for i := 0; i < numKeys; i++ {
+			k := rand.Intn(numKeys * 2)
+			switch rand.Intn(3) {
+			case 0:
+				b.Insert(k, i)
+				m[k] = []int{i}
+			case 1:
+				b.Append(k, i)
+				m[k] = append(m[k], i)
+			case 2:
+				b.Delete(k)
+				delete(m, k)
+			}
+		}
+		if b.Len() == 0 || b.Len() > numKeys {
+			T.Fatalf("invalid batch len: %d", b.Len())
+		}
+		for k := -1; k <= numKeys*2; k++ {
+			vals, ok := b.FindAll(k)
+			if ok != (len(m[k]) != 0) || len(vals) != len(m[k]) {
+				failf(T, t, "batch FindAll(%d): %v, needed %v", k, vals, m[k])
+			}
+			for i := range vals {
+				if vals[i] != m[k][i] {
+					failf(T, t, "batch FindAll(%d): %v, needed %v", k, vals, m[k])
+				}
+			}
+			if v, ok := b.Find(k); ok && v != vals[0] {
+				failf(T, t, "batch Find(%d): %d, needed %d", k, v, vals[0])
+			}
+		}
+		compareItems(T, t, "batch Range", b.Range(nil, nil), mapEntries(m))
+		from, to := numKeys/2, numKeys
+		var needed []KeyValue[int, int]
+		for _, kv := range mapEntries(m) {
+			if kv.Key >= from && kv.Key < to {
+				needed = append(needed, kv)
+			}
+		}
+		compareItems(T, t, "batch Range", b.Range(&from, &to), needed)
+		b.Flush()
+		if b.Len() != 0 {
+			T.Fatalf("batch is not empty after flush: %d", b.Len())
+		}
+		compareWithMap(T, t, m)
+	}
+	b.Insert(-1, -1)
+	b.Discard()
+	b.Flush()
+	if _, ok := t.Find(-1); ok {
+		fail(T, t, "discarded mutation applied")
+	}
+}
+
+func TestBatchCommitIf(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	b := t.NewBatch()
+	v := b.Version()
+	b.Insert(1, 1)
+	if err := b.CommitIf(v); err != nil {
+		fail(T, t, err)
+	}
+	if b.Version() == v {
+		fail(T, t, "batch version not changed after commit")
+	}
+	v = b.Version()
+	b.Insert(2, 2)
+	t.Insert(3, 3)
+	if err := b.CommitIf(v); err != ErrConflict {
+		failf(T, t, "commit after tree modification: %v", err)
+	}
+	if b.Len() != 1 {
+		failf(T, t, "pending mutations dropped on conflict: %d", b.Len())
+	}
+	if _, ok := t.Find(2); ok {
+		fail(T, t, "conflicting batch applied")
+	}
+	b.Flush()
+	v = b.Version()
+	t.Delete(-1)
+	if err := b.CommitIf(v); err != nil {
+		failf(T, t, "commit after failed delete: %v", err)
+	}
+	for _, f := range []func(){
+		func() { t.Insert(1, 1) },
+		func() { t.Append(1, 1) },
+		func() { t.Delete(1) },
+		func() { t.Clear() },
+	} {
+		v = b.Version()
+		f()
+		if err := b.CommitIf(v); err != ErrConflict {
+			failf(T, t, "commit after tree modification: %v", err)
+		}
+		b.Flush()
+	}
+}
+
+func TestBatchApply(T *testing.T) {
+	keys, values, t, _ := makeTreeAppend(T, MinOrder, numKeys)
+	_, _, t2, _ := makeTreeAppendWithKeysValues(T, MinOrder, keys, values)
+	_, _, t3, _ := makeTreeAppendWithKeysValues(T, MinOrder, keys, values)
+	for _, t := range []*BPTree[int, int]{t, t2} {
+		t.SetLazyDeletion(true)
+		t.SetMemoryCost(func(int, int) int64 { return 1 })
+		for k := 0; k < numKeys; k += 10 {
+			t.DeleteAll(k)
+		}
+	}
+	var log bytes.Buffer
+	t.Record(&log)
+	b := t.NewBatch()
+	for i := 0; i < numKeys; i++ {
+		k := rand.Intn(numKeys * 2)
+		switch rand.Intn(4) {
+		case 0:
+			b.Insert(k, i)
+			t2.Insert(k, i)
+		case 1, 2:
+			b.Append(k, i)
+			t2.Append(k, i)
+		case 3:
+			b.Delete(k)
+			t2.DeleteAll(k)
+		}
+	}
+	b.Flush()
+	if err := t.StopRecording(); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t, "Flush", t.Entries(), t2.Entries())
+	if t.Size() != t2.Size() || t.memCost != t2.memCost {
+		failf(T, t, "size %d and memory cost %d, needed %d and %d", t.Size(), t.memCost, t2.Size(), t2.memCost)
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+	t3.SetLazyDeletion(true)
+	for k := 0; k < numKeys; k += 10 {
+		t3.DeleteAll(k)
+	}
+	if err := t3.Replay(&log); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t3, "Replay", t3.Entries(), t.Entries())
+}
+
+func TestBatchInsertInPlace(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, MinOrder, numKeys)
+	c := t.Counters()
+	b := t.NewBatch()
+	for k := range m {
+		b.Insert(k, -k)
+		m[k] = []int{-k}
+	}
+	b.Flush()
+	compareWithMap(T, t, m)
+	if c2 := t.Counters(); c2.Splits != c.Splits || c2.Merges != c.Merges || c2.Borrows != c.Borrows {
+		failf(T, t, "staged inserts of present keys restructured tree: %+v, before %+v", c2, c)
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
+
+func TestBatchNaN(T *testing.T) {
+	t := NewBPTree[float64, int](bmax)
+	b := t.NewBatch()
+	b.Insert(math.NaN(), 1)
+	b.Append(math.NaN(), 2)
+	b.Append(1, 3)
+	if b.Len() != 2 {
+		T.Fatalf("batch len %d, needed 2", b.Len())
+	}
+	if vals, _ := b.FindAll(math.NaN()); !slices.Equal(vals, []int{1, 2}) {
+		T.Fatalf("batch FindAll(NaN): %v", vals)
+	}
+	b.Flush()
+	if vals, _ := t.FindAll(math.NaN()); !slices.Equal(vals, []int{1, 2}) || t.Size() != 3 {
+		T.Fatalf("FindAll(NaN) after flush: %v, size %d", vals, t.Size())
+	}
+}
+
+func TestBatchFindAllOrder(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	t.SetFindAllOrder(NewestFirst)
+	t.Append(1, 1)
+	t.Append(1, 2)
+	b := t.NewBatch()
+	b.Append(1, 3)
+	vals, _ := b.FindAll(1)
+	b.Flush()
+	if needed, _ := t.FindAll(1); !slices.Equal(vals, needed) {
+		T.Fatalf("batch FindAll: %v, needed %v", vals, needed)
+	}
+}
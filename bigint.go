@@ -0,0 +1,77 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidBigIntKey is returned by ParseBigIntKey for strings not produced by BigIntKey.
+var ErrInvalidBigIntKey = errors.New("invalid big.Int key")
+
+const (
+	bigIntNegative = iota
+	bigIntZero
+	bigIntPositive
+)
+
+// BigIntKey encodes x as a string key, such that keys compare in the same order as the numbers.
+// The key takes 5 bytes plus the magnitude of x, e.g. 37 bytes for a 256-bit number,
+// and ParseBigIntKey decodes it back.
+func BigIntKey(x *big.Int) string {
+	sign := x.Sign()
+	if sign == 0 {
+		return string([]byte{bigIntZero})
+	}
+	mag := x.Bytes()
+	b := make([]byte, 5+len(mag))
+	b[0] = bigIntPositive
+	binary.BigEndian.PutUint32(b[1:5], uint32(len(mag)))
+	copy(b[5:], mag)
+	if sign < 0 {
+		// greater magnitudes of negative numbers must be less, so length and magnitude are inverted
+		b[0] = bigIntNegative
+		for i := 1; i < len(b); i++ {
+			b[i] = ^b[i]
+		}
+	}
+	return string(b)
+}
+
+// ParseBigIntKey decodes a key produced by BigIntKey.
+func ParseBigIntKey(key string) (*big.Int, error) {
+	if len(key) == 1 && key[0] == bigIntZero {
+		return new(big.Int), nil
+	}
+	if len(key) < 5 || (key[0] != bigIntNegative && key[0] != bigIntPositive) {
+		return nil, ErrInvalidBigIntKey
+	}
+	b := []byte(key)
+	if b[0] == bigIntNegative {
+		for i := 1; i < len(b); i++ {
+			b[i] = ^b[i]
+		}
+	}
+	if int(binary.BigEndian.Uint32(b[1:5])) != len(b)-5 || len(b) == 5 || b[5] == 0 {
+		return nil, ErrInvalidBigIntKey
+	}
+	x := new(big.Int).SetBytes(b[5:])
+	if b[0] == bigIntNegative {
+		x.Neg(x)
+	}
+	return x, nil
+}
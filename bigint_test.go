@@ -0,0 +1,62 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBigIntKey(T *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var nums []*big.Int
+	for _, bits := range []uint{0, 1, 7, 8, 9, 64, 255, 256, 300} {
+		for i := 0; i < 10; i++ {
+			x := new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), bits))
+			nums = append(nums, x, new(big.Int).Neg(x))
+		}
+	}
+	t := NewBPTree[string, *big.Int](bmax)
+	for _, x := range nums {
+		key := BigIntKey(x)
+		y, err := ParseBigIntKey(key)
+		if err != nil || y.Cmp(x) != 0 {
+			T.Fatalf("parse key of %s: (%v, %v)", x, y, err)
+		}
+		t.Insert(key, x)
+	}
+	for _, x := range nums {
+		for _, y := range nums {
+			if c := strings.Compare(BigIntKey(x), BigIntKey(y)); c != x.Cmp(y) {
+				T.Fatalf("keys of %s and %s compare as %d", x, y, c)
+			}
+		}
+	}
+	var prev *big.Int
+	for _, kv := range t.Entries() {
+		x := kv.Value.(*big.Int)
+		if prev != nil && prev.Cmp(x) >= 0 {
+			T.Fatalf("%s is not less than %s", prev, x)
+		}
+		prev = x
+	}
+	for _, key := range []string{"", "\x03", "\x01\x00", "\x02\x00\x00\x00\x02\x01", "\x02\x00\x00\x00\x01\x00", "\x02\x00\x00\x00\x00"} {
+		if _, err := ParseBigIntKey(key); err != ErrInvalidBigIntKey {
+			T.Errorf("parse %q: %v", key, err)
+		}
+	}
+}
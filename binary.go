@@ -0,0 +1,150 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrInvalidFormat is returned by ReadBinary if data is not written by WriteBinary.
+var ErrInvalidFormat = errors.New("invalid binary tree format")
+
+// binaryMagic starts data written by WriteBinary, and its last byte is the format version.
+var binaryMagic = [8]byte{'B', 'P', 'T', 'R', 'E', 'E', 0, 1}
+
+// WriteBinary writes tree to w in a binary format which ReadBinary reads back, encoding keys and values
+// with given codecs. The format is:
+//
+//   - 8 bytes of magic "BPTREE\x00\x01", where the last byte is the format version;
+//   - tree order as a 4-byte big-endian unsigned integer;
+//   - number of distinct keys as an 8-byte big-endian unsigned integer;
+//   - for every key in ascending order, the encoded key prefixed with its length as a 4-byte big-endian
+//     unsigned integer, the number of values of key as a 4-byte big-endian unsigned integer, and
+//     encoded values in the order they were appended, each prefixed with its length like the key.
+func (t *BPTree[K, V]) WriteBinary(w io.Writer, keys KeyCodec[K], values ValueCodec[V]) error {
+	bw := bufio.NewWriter(w)
+	var header [20]byte
+	copy(header[:], binaryMagic[:])
+	binary.BigEndian.PutUint32(header[8:], uint32(t.order()))
+	binary.BigEndian.PutUint64(header[12:], uint64(t.Stats().Keys))
+	if _, err := bw.Write(header[:]); err != nil {
+		return err
+	}
+	for n := t.firstLeaf(); n != nil; n = n.right {
+		for i, key := range n.keys {
+			var vals collision[V]
+			switch v := n.values[i].(type) {
+			case tombstone:
+				continue
+			case collision[V]:
+				vals = v
+			default:
+				vals = collision[V]{v.(V)}
+			}
+			kb, err := keys.Encode(key)
+			if err != nil {
+				return err
+			}
+			if err := writeRecordField(bw, kb); err != nil {
+				return err
+			}
+			var count [4]byte
+			binary.BigEndian.PutUint32(count[:], uint32(len(vals)))
+			if _, err := bw.Write(count[:]); err != nil {
+				return err
+			}
+			for _, v := range vals {
+				vb, err := values.Encode(v)
+				if err != nil {
+					return err
+				}
+				if err := writeRecordField(bw, vb); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadBinary reads a tree written by WriteBinary from r, decoding keys and values with given codecs.
+// Tree is built bottom-up with fully filled nodes of the written order. Returns ErrInvalidFormat if data
+// is malformed, or keys are not in ascending order.
+func ReadBinary[K Key, V any](r io.Reader, keys KeyCodec[K], values ValueCodec[V]) (*BPTree[K, V], error) {
+	br := bufio.NewReader(r)
+	var header [20]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrInvalidFormat
+		}
+		return nil, err
+	}
+	if [8]byte(header[:8]) != binaryMagic {
+		return nil, ErrInvalidFormat
+	}
+	order := binary.BigEndian.Uint32(header[8:])
+	numKeys := binary.BigEndian.Uint64(header[12:])
+	if order < MinOrder || order > MaxOrder {
+		return nil, ErrInvalidFormat
+	}
+	// keys and values are not preallocated by their numbers, so corrupted ones can not cause a huge allocation
+	var ukeys []K
+	var uvalues []any
+	for i := uint64(0); i < numKeys; i++ {
+		kb, err := readRecordField(br, false)
+		if err != nil {
+			return nil, err
+		}
+		key, err := keys.Decode(kb)
+		if err != nil {
+			return nil, err
+		}
+		if len(ukeys) != 0 && !cmp.Less(ukeys[len(ukeys)-1], key) {
+			return nil, ErrInvalidFormat
+		}
+		var count [4]byte
+		if _, err := io.ReadFull(br, count[:]); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		var vals collision[V]
+		for j := binary.BigEndian.Uint32(count[:]); j > 0; j-- {
+			vb, err := readRecordField(br, false)
+			if err != nil {
+				return nil, err
+			}
+			v, err := values.Decode(vb)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+		switch len(vals) {
+		case 0:
+			return nil, ErrInvalidFormat
+		case 1:
+			uvalues = append(uvalues, vals[0])
+		default:
+			uvalues = append(uvalues, vals)
+		}
+		ukeys = append(ukeys, key)
+	}
+	t := buildTree[K, V](int(order), ukeys, uvalues)
+	t.counters.Inserts = uint64(t.size)
+	return t, nil
+}
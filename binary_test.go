@@ -0,0 +1,79 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteReadBinary(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	deleted := t.Entries()[0].Key
+	t.DeleteAll(deleted)
+	delete(m, deleted)
+	var buf bytes.Buffer
+	if err := t.WriteBinary(&buf, OrderedKeyCodec[int]{}, GobCodec[int]{}); err != nil {
+		T.Fatal(err)
+	}
+	data := buf.Bytes()
+	t2, err := ReadBinary[int, int](bytes.NewReader(data), OrderedKeyCodec[int]{}, GobCodec[int]{})
+	if err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, t2, m)
+	if t2.order() != bmax {
+		failf(T, t2, "order %d, needed %d", t2.order(), bmax)
+	}
+
+	for _, n := range []int{0, 7, 20, len(data) - 1} {
+		if _, err := ReadBinary[int, int](bytes.NewReader(data[:n]), OrderedKeyCodec[int]{}, GobCodec[int]{}); err == nil {
+			failf(T, t, "truncated to %d bytes data read", n)
+		}
+	}
+	corrupted := bytes.Clone(data)
+	corrupted[0] = 'X'
+	if _, err := ReadBinary[int, int](bytes.NewReader(corrupted), OrderedKeyCodec[int]{}, GobCodec[int]{}); err != ErrInvalidFormat {
+		failf(T, t, "invalid magic: %v", err)
+	}
+
+	var empty bytes.Buffer
+	if err := NewBPTree[int, int](MinOrder).WriteBinary(&empty, OrderedKeyCodec[int]{}, GobCodec[int]{}); err != nil {
+		T.Fatal(err)
+	}
+	if t3, err := ReadBinary[int, int](&empty, OrderedKeyCodec[int]{}, GobCodec[int]{}); err != nil || t3.Size() != 0 {
+		T.Fatalf("empty tree read: %v", err)
+	}
+}
+
+func TestWriteReadBinaryMaxOrder(T *testing.T) {
+	t := NewBPTree[int, int](MaxOrder + 1)
+	if t.order() != MaxOrder {
+		failf(T, t, "order %d, needed %d", t.order(), MaxOrder)
+	}
+	t.Insert(1, 1)
+	var buf bytes.Buffer
+	if err := t.WriteBinary(&buf, OrderedKeyCodec[int]{}, GobCodec[int]{}); err != nil {
+		T.Fatal(err)
+	}
+	t2, err := ReadBinary[int, int](&buf, OrderedKeyCodec[int]{}, GobCodec[int]{})
+	if err != nil {
+		T.Fatal(err)
+	}
+	if t2.order() != MaxOrder || t2.Size() != 1 {
+		failf(T, t2, "order %d and size %d, needed %d and 1", t2.order(), t2.Size(), MaxOrder)
+	}
+}
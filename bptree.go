@@ -17,11 +17,18 @@
 package bptree
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"math"
+	"time"
+	"unsafe"
 )
 
+// Key is a constraint for keys of the tree. Keys are ordered and compared with cmp.Compare,
+// so for floating-point keys NaN is less than any other value and equal to itself.
 type Key interface {
-	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~string
+	cmp.Ordered
 }
 
 type KeyValue[K Key, V any] struct {
@@ -37,31 +44,84 @@ type Iterator[K Key, V any] interface {
 
 const MinOrder = 3
 
+// MaxOrder is the maximal order of a tree. Nodes allocate slots for order pairs up front,
+// so larger orders only waste memory.
+const MaxOrder = 1 << 16
+
+// autoOrderNodeSize is a size in bytes of key and value slots of a leaf node that AutoOrder aims at.
+const autoOrderNodeSize = 4096
+
 type BPTree[K Key, V any] struct {
-	root *node[K, V]
-	size int
+	root     *node[K, V]
+	size     int
+	counters Counters
+	pprofCtx context.Context
+
+	lazyDeletion bool
+	tombstones   int
+
+	version uint64
+
+	deleteOldest bool // see SetDeleteOrder, the zero value is its NewestFirst default
+	findAllOrder DuplicateOrder
+	noDuplicates bool // Append replaces values like Insert, see WithAllowDuplicates
+
+	slowOps   *slowOpHook[K]
+	memLimit  int64
+	memCostFn func(K, V) int64
+	memCost   int64
+	recorder  *recorder[K, V]
+
+	freeLeaves    []*node[K, V] // nodes removed by merges, reused by splits
+	freeInternals []*node[K, V]
+	noFreeList    bool // see WithFreeList
+	keysOnly      bool // leaves have no value slots, see BPSet
 }
 
 // NewBPTree returns a new BPTree. Order measures the capacity of nodes, i.e. maximum allowed
 // number of direct child nodes for internal nodes, and maximum key-value pairs for leaf nodes.
-// Order should be greater or equal MinOrder, otherwise BPTree will be initialized with MinOrder.
+// If order is 0, it is chosen automatically with AutoOrder. Otherwise order should be in [MinOrder; MaxOrder],
+// or BPTree will be initialized with the nearest of them (NewBPTreeFromOptions returns an error instead).
 func NewBPTree[K Key, V any](order int) *BPTree[K, V] {
-	if order < MinOrder {
-		order = MinOrder
+	if order == 0 {
+		order = AutoOrder[K]()
 	}
+	order = min(max(order, MinOrder), MaxOrder)
 	return &BPTree[K, V]{
 		root: newLeafNode[K, V](order),
 	}
 }
 
+// AutoOrder returns an order for keys of type K, such that key and value slots of a leaf node
+// take about a memory page (4 KiB).
+func AutoOrder[K Key]() int {
+	var k K
+	var v any
+	return max(MinOrder, autoOrderNodeSize/int(unsafe.Sizeof(k)+unsafe.Sizeof(v)))
+}
+
 // Clear tree.
 func (t *BPTree[K, V]) Clear() {
-	if t.root.isLeaf() {
-		t.root = newLeafNode[K, V](cap(t.root.keys))
+	if t.recorder != nil {
+		t.recorder.record(logRecord[K, V]{Op: recordClear})
+	}
+	if t.keysOnly {
+		t.root = newKeysLeafNode[K, V](t.order())
 	} else {
-		t.root = newLeafNode[K, V](cap(t.root.children))
+		t.root = newLeafNode[K, V](t.order())
 	}
 	t.size = 0
+	t.tombstones = 0
+	t.memCost = 0
+	t.version++
+}
+
+// order returns the order tree was created with.
+func (t *BPTree[K, V]) order() int {
+	if t.root.isLeaf() {
+		return cap(t.root.keys)
+	}
+	return cap(t.root.children)
 }
 
 // Size returns a number of key-value pairs currently stored in a tree.
@@ -69,6 +129,12 @@ func (t *BPTree[K, V]) Size() int {
 	return t.size
 }
 
+// Version returns a generation number of tree, which changes on every modification. It lets caches
+// built above the tree cheaply check whether anything has changed since they were filled.
+func (t *BPTree[K, V]) Version() uint64 {
+	return t.version
+}
+
 // Find returns a (value, true) for a given key, or (nil, false) if not found.
 func (t *BPTree[K, V]) Find(key K) (V, bool) {
 	if v, ok := t.find(key); ok {
@@ -82,9 +148,17 @@ func (t *BPTree[K, V]) Find(key K) (V, bool) {
 }
 
 // FindAll returns a ([]value, true) for a given key, or (nil, false) if not found.
+// Values are returned in the order set by SetFindAllOrder.
 func (t *BPTree[K, V]) FindAll(key K) ([]V, bool) {
 	if v, ok := t.find(key); ok {
 		if v, ok := v.(collision[V]); ok {
+			if t.findAllOrder == NewestFirst {
+				r := make([]V, len(v))
+				for i, val := range v {
+					r[len(v)-1-i] = val
+				}
+				return r, true
+			}
 			return v, true
 		}
 		return []V{v.(V)}, true
@@ -92,59 +166,106 @@ func (t *BPTree[K, V]) FindAll(key K) ([]V, bool) {
 	return nil, false
 }
 
+// Contains returns true if a given key is present in tree. Unlike Find, it does not retrieve a value.
+func (t *BPTree[K, V]) Contains(key K) bool {
+	_, ok := t.find(key)
+	return ok
+}
+
 func (t *BPTree[K, V]) find(key K) (any, bool) {
-	n := t.root
-NodesLoop:
-	for n.isInternal() {
-		for i, c := range n.children {
-			if i == len(n.keys) || key < n.keys[i] {
-				n = c
-				continue NodesLoop
-			}
+	n := t.seekLeaf(key)
+	if i, found := n.search(key); found {
+		if t.keysOnly {
+			var zero V
+			return zero, true
 		}
-	}
-	for i, k := range n.keys {
-		if k == key {
-			return n.values[i], true
+		if _, ok := n.values[i].(tombstone); ok {
+			return nil, false
 		}
+		return n.values[i], true
 	}
 	return nil, false
 }
 
+// seekLeaf returns the leaf node where a given key is stored or should be stored.
+func (t *BPTree[K, V]) seekLeaf(key K) *node[K, V] {
+	n := t.root
+	for n.isInternal() {
+		n = n.children[n.childIndex(key)]
+	}
+	return n
+}
+
 // Insert puts a key-value pair to the tree. If given key is present in tree, it's value will be replaced.
 func (t *BPTree[K, V]) Insert(key K, val V) {
 	t.insert(key, val, true)
 }
 
-// Append puts a key-value pair to the tree. If given key is present in tree, val will be appended to it's values.
+// Append puts a key-value pair to the tree. If given key is present in tree, val will be appended to it's values,
+// unless tree is created with WithAllowDuplicates(false).
 func (t *BPTree[K, V]) Append(key K, val V) {
 	t.insert(key, val, false)
 }
 
 func (t *BPTree[K, V]) insert(key K, val V, replace bool) {
-	n := t.root
-	ok, key2, n2 := n.insert(key, val, replace)
-	if n2 != nil {
-		if n.isLeaf() {
-			t.root = newInternalNode[K, V](cap(n.keys))
-		} else {
-			t.root = newInternalNode[K, V](cap(n.children))
+	replace = replace || t.noDuplicates
+	if t.recorder != nil {
+		op := recordInsert
+		if !replace {
+			op = recordAppend
 		}
-		t.root.keys = t.root.keys[:1]
-		t.root.keys[0] = key2
-		t.root.children = t.root.children[:2]
-		t.root.children[0] = n
-		t.root.children[1] = n2
+		t.recorder.record(logRecord[K, V]{Op: op, Key: key, Value: val})
 	}
-	if ok {
-		t.size++
+	if t.memCostFn != nil {
+		if replace {
+			if old, ok := t.find(key); ok {
+				t.memCost -= t.valuesCost(key, old)
+			}
+		}
+		t.memCost += t.memCostFn(key, val)
 	}
+	if t.slowOps != nil {
+		op := "insert"
+		if !replace {
+			op = "append"
+		}
+		defer t.observePoint(op, key, time.Now(), t.restructures())
+	}
+	added, key2, n2 := t.root.insert(t, key, val, replace)
+	t.version++
+	if n2 != nil {
+		t.growRoot(key2, n2)
+	}
+	t.size += added
+	if added > 0 {
+		t.counters.Inserts++
+	}
+}
+
+// growRoot puts a new root above the split root and its new right sibling n2 with minimal key key2.
+func (t *BPTree[K, V]) growRoot(key2 K, n2 *node[K, V]) {
+	n := t.root
+	if n.isLeaf() {
+		t.root = t.allocInternal(cap(n.keys))
+	} else {
+		t.root = t.allocInternal(cap(n.children))
+	}
+	t.root.keys = t.root.keys[:1]
+	t.root.keys[0] = key2
+	t.root.children = t.root.children[:2]
+	t.root.children[0] = n
+	t.root.children[1] = n2
+	t.root.count = n.count + n2.count
 }
 
 // Delete removes a key-value pair and returns it's (value, true) if success, or (nil, false) if not found.
-// If multiply values are found, last added will be removed.
+// If multiply values are found, last added will be removed, unless SetDeleteOrder(OldestFirst) was called.
 func (t *BPTree[K, V]) Delete(key K) (val V, ok bool) {
-	if v, ok := t.delete(key, false, -1); ok {
+	idx := -1
+	if t.deleteOldest {
+		idx = 0
+	}
+	if v, ok := t.delete(key, false, idx); ok {
 		return v.(V), true
 	}
 	return
@@ -167,33 +288,75 @@ func (t *BPTree[K, V]) DeleteAll(key K) (vals []V, ok bool) {
 }
 
 func (t *BPTree[K, V]) delete(key K, all bool, idx int) (val any, ok bool) {
-	val, ok = t.root.delete(key, all, idx)
+	if t.recorder != nil {
+		if all {
+			t.recorder.record(logRecord[K, V]{Op: recordDeleteAll, Key: key})
+		} else {
+			t.recorder.record(logRecord[K, V]{Op: recordDelete, Key: key, Idx: idx})
+		}
+	}
+	if t.slowOps != nil {
+		defer t.observePoint("delete", key, time.Now(), t.restructures())
+	}
+	val, ok = t.root.delete(t, key, all, idx)
 	if ok {
-		if t.root.isInternal() && len(t.root.children) == 1 {
-			t.root = t.root.children[0]
+		if t.memCostFn != nil {
+			t.memCost -= t.valuesCost(key, val)
 		}
+		t.version++
+		t.shrinkRoot()
 		if all {
 			c, _ := val.(collision[V])
 			t.size -= len(c)
+			t.counters.Deletes += uint64(len(c))
 			return c, true
 		} else {
 			t.size--
+			t.counters.Deletes++
 		}
 	}
 	return
 }
 
-type iterator[K Key, V any] struct {
-	from *K
-	to   *K
-	n    *node[K, V]
-	i    int
-	c    collision[V]
-	ckey K
-	ci   int
+// shrinkRoot replaces an internal root left with a single child by the child.
+func (t *BPTree[K, V]) shrinkRoot() {
+	if t.root.isInternal() && len(t.root.children) == 1 {
+		old := t.root
+		t.root = old.children[0]
+		t.freeNode(old)
+	}
+}
+
+// ErrModified is returned by RangeIterator.Err if the tree has been modified during iteration.
+var ErrModified = errors.New("tree modified during iteration")
+
+// RangeIterator is an Iterator over key-value pairs of a tree from an interval. It can be reused
+// for another interval with Reset, avoiding allocation of a new iterator. Iterator is fail-fast:
+// if the tree is modified after Reset, Next stops the iteration, and Err returns ErrModified.
+type RangeIterator[K Key, V any] struct {
+	t       *BPTree[K, V]
+	from    *K
+	to      *K
+	n       *node[K, V]
+	i       int
+	c       collision[V]
+	ckey    K
+	ci      int
+	hops    int    // moves to the right sibling leaf, reported to slow operation hook
+	version uint64 // version of tree at Reset
+	err     error
+}
+
+// Err returns ErrModified if the iteration has been stopped by a modification of the tree, or nil.
+func (i *RangeIterator[K, V]) Err() error {
+	return i.err
 }
 
-func (i *iterator[K, V]) Next() (KeyValue[K, V], bool) {
+func (i *RangeIterator[K, V]) Next() (KeyValue[K, V], bool) {
+	if i.n != nil && i.version != i.t.version {
+		i.n = nil
+		i.err = ErrModified
+	}
 SEARCH:
 	for i.n != nil {
 		if i.c != nil {
@@ -206,13 +369,16 @@ SEARCH:
 		}
 		for ; i.i < len(i.n.keys); i.i++ {
 			k := i.n.keys[i.i]
-			if i.from != nil && k < *i.from {
+			if i.from != nil && cmp.Less(k, *i.from) {
 				continue
 			}
-			if i.to != nil && k >= *i.to {
+			if i.to != nil && !cmp.Less(k, *i.to) {
 				i.n = nil
 				break SEARCH
 			}
+			if _, ok := i.n.values[i.i].(tombstone); ok {
+				continue
+			}
 			if c, ok := i.n.values[i.i].(collision[V]); ok {
 				i.c = c
 				i.ckey = i.n.keys[i.i]
@@ -227,6 +393,7 @@ SEARCH:
 		}
 		i.n = i.n.right
 		i.i = 0
+		i.hops++
 	}
 	return KeyValue[K, V]{}, false
 }
@@ -234,33 +401,56 @@ SEARCH:
 // Iterator returns an Iterator for key-value pairs from interval [*from; *to). Nil given as a parameter will
 // be interpreted as begin or end whole tree key diapason.
 func (t *BPTree[K, V]) Iterator(from *K, to *K) Iterator[K, V] {
-	if from != nil && to != nil && *from >= *to {
-		return &iterator[K, V]{}
+	return t.NewIterator(from, to)
+}
+
+// NewIterator is like Iterator, but returns a concrete RangeIterator which can be reused with Reset.
+func (t *BPTree[K, V]) NewIterator(from *K, to *K) *RangeIterator[K, V] {
+	i := &RangeIterator[K, V]{t: t}
+	i.Reset(from, to)
+	return i
+}
+
+// Reset positions the iterator at the beginning of interval [*from; *to), with the same meaning
+// of parameters as for Iterator.
+func (i *RangeIterator[K, V]) Reset(from *K, to *K) {
+	*i = RangeIterator[K, V]{
+		t:       i.t,
+		from:    from,
+		to:      to,
+		version: i.t.version,
 	}
-	n := t.root
-NodesLoop:
+	if from != nil && to != nil && !cmp.Less(*from, *to) {
+		return
+	}
+	n := i.t.root
 	for n.isInternal() {
-		for i, c := range n.children {
-			if from == nil || i == len(n.keys) || *from < n.keys[i] {
-				n = c
-				continue NodesLoop
-			}
+		if from == nil {
+			n = n.children[0]
+		} else {
+			n = n.children[n.childIndex(*from)]
 		}
 	}
-	return &iterator[K, V]{
-		from: from,
-		to:   to,
-		n:    n,
-	}
+	i.n = n
 }
 
 // Range returns a slice of key-value pairs from interval [*from; *to). Nil given as a parameter will
 // be interpreted as begin or end whole tree key diapason. If there are no keys found, returns nil.
-func (t *BPTree[K, V]) Range(from *K, to *K) []KeyValue[K, V] {
-	i := t.Iterator(from, to)
-	var result []KeyValue[K, V]
-	for kv, ok := i.Next(); ok; kv, ok = i.Next() {
-		result = append(result, kv)
+func (t *BPTree[K, V]) Range(from *K, to *K) (result []KeyValue[K, V]) {
+	var start time.Time
+	if t.slowOps != nil {
+		start = time.Now()
+	}
+	var i RangeIterator[K, V]
+	t.withLabels("range", func() {
+		i.t = t
+		i.Reset(from, to)
+		for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+			result = append(result, kv)
+		}
+	})
+	if t.slowOps != nil {
+		t.observeRange("range", from, to, start, t.Height()+i.hops)
 	}
 	return result
 }
@@ -270,36 +460,113 @@ func (t *BPTree[K, V]) Entries() []KeyValue[K, V] {
 	return t.Range(nil, nil)
 }
 
-// First returns (key-value, true) for the minimal key in tree, or (zero, false) if tree is empty.
-func (t *BPTree[K, V]) First() (KeyValue[K, V], bool) {
-	if t.size == 0 {
-		return KeyValue[K, V]{}, false
+// ValuesInRange is like Range, but returns only values.
+func (t *BPTree[K, V]) ValuesInRange(from *K, to *K) []V {
+	return t.AppendValuesInRange(nil, from, to)
+}
+
+// AppendValuesInRange appends values from interval [*from; *to) to dst and returns the extended slice,
+// reusing dst if it has enough capacity.
+func (t *BPTree[K, V]) AppendValuesInRange(dst []V, from *K, to *K) []V {
+	var start time.Time
+	if t.slowOps != nil {
+		start = time.Now()
 	}
-	n := t.root
-	for n.isInternal() {
-		n = n.children[0]
+	var i RangeIterator[K, V]
+	t.withLabels("values", func() {
+		i.t = t
+		i.Reset(from, to)
+		for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+			dst = append(dst, kv.Value.(V))
+		}
+	})
+	if t.slowOps != nil {
+		t.observeRange("values", from, to, start, t.Height()+i.hops)
 	}
-	v := n.values[0]
-	if c, ok := v.(collision[V]); ok {
-		v = c[0]
+	return dst
+}
+
+// First returns (key-value, true) for the minimal key in tree, or (zero, false) if tree is empty.
+func (t *BPTree[K, V]) First() (KeyValue[K, V], bool) {
+	if k, v, ok := t.Cursor().First(); ok {
+		return KeyValue[K, V]{Key: k, Value: v}, true
 	}
-	return KeyValue[K, V]{Key: n.keys[0], Value: v}, true
+	return KeyValue[K, V]{}, false
 }
 
 // Last returns (key-value, true) for the maximal key in tree, or (zero, false) if tree is empty.
 func (t *BPTree[K, V]) Last() (KeyValue[K, V], bool) {
+	if k, v, ok := t.Cursor().Last(); ok {
+		return KeyValue[K, V]{Key: k, Value: v}, true
+	}
+	return KeyValue[K, V]{}, false
+}
+
+// PopFirst removes the first key-value pair, i.e. the pair of the minimal key appended first,
+// and returns (key-value, true), or (zero, false) if tree is empty.
+func (t *BPTree[K, V]) PopFirst() (KeyValue[K, V], bool) {
+	return t.pop(false)
+}
+
+// PopLast removes the last key-value pair, i.e. the pair of the maximal key appended last,
+// and returns (key-value, true), or (zero, false) if tree is empty.
+func (t *BPTree[K, V]) PopLast() (KeyValue[K, V], bool) {
+	return t.pop(true)
+}
+
+// pop removes the first or the last pair with a single descent, keeping the bookkeeping of delete.
+func (t *BPTree[K, V]) pop(last bool) (KeyValue[K, V], bool) {
 	if t.size == 0 {
 		return KeyValue[K, V]{}, false
 	}
+	key, val := t.root.pop(t, last)
+	if t.recorder != nil {
+		idx := 0
+		if last {
+			idx = -1
+		}
+		t.recorder.record(logRecord[K, V]{Op: recordDelete, Key: key, Idx: idx})
+	}
+	if t.memCostFn != nil {
+		t.memCost -= t.valuesCost(key, val)
+	}
+	t.version++
+	t.shrinkRoot()
+	t.size--
+	t.counters.Deletes++
+	return KeyValue[K, V]{Key: key, Value: val.(V)}, true
+}
+
+// MinKey returns (key, true) for the minimal key in tree, or (zero, false) if tree is empty.
+// Unlike First, it does not retrieve a value.
+func (t *BPTree[K, V]) MinKey() (K, bool) {
+	for n := t.firstLeaf(); n != nil; n = n.right {
+		for i, v := range n.values {
+			if _, ok := v.(tombstone); !ok {
+				return n.keys[i], true
+			}
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// MaxKey returns (key, true) for the maximal key in tree, or (zero, false) if tree is empty.
+// Unlike Last, it does not retrieve a value.
+func (t *BPTree[K, V]) MaxKey() (K, bool) {
 	n := t.root
 	for n.isInternal() {
 		n = n.children[len(n.children)-1]
 	}
-	v := n.values[len(n.values)-1]
-	if c, ok := v.(collision[V]); ok {
-		v = c[len(c)-1]
+	for ; n != nil; n = n.left {
+		for i := len(n.values) - 1; i >= 0; i-- {
+			if _, ok := n.values[i].(tombstone); !ok {
+				return n.keys[i], true
+			}
+		}
 	}
-	return KeyValue[K, V]{Key: n.keys[len(n.keys)-1], Value: v}, true
+	var zero K
+	return zero, false
 }
 
 type node[K Key, V any] struct {
@@ -309,6 +576,7 @@ type node[K Key, V any] struct {
 	left     *node[K, V]
 	right    *node[K, V]
 	bmin     int
+	count    int // number of key-value pairs in subtree
 }
 
 func newInternalNode[K Key, V any](size int) *node[K, V] {
@@ -327,65 +595,113 @@ func newLeafNode[K Key, V any](size int) *node[K, V] {
 	}
 }
 
+// newKeysLeafNode returns a leaf without value slots for trees of BPSet, whose values are all empty.
+func newKeysLeafNode[K Key, V any](size int) *node[K, V] {
+	return &node[K, V]{
+		keys: make([]K, 0, size),
+		bmin: int(math.Ceil(float64(size) / 2)),
+	}
+}
+
 func (n *node[K, V]) isInternal() bool {
 	return n.children != nil
 }
 
 func (n *node[K, V]) isLeaf() bool {
-	return n.values != nil
+	return n.children == nil
 }
 
-func (n *node[K, V]) insert(key K, val V, replace bool) (ok bool, key2 K, n2 *node[K, V]) {
-	if n.isLeaf() {
-		return n.insertToLeaf(key, val, replace)
+// search returns the index of the first key of node greater or equal to a given key, and whether
+// it is equal. Nodes are searched in binary, which keeps the number of comparisons logarithmic
+// in order, since comparisons dominate lookups with costly keys such as long strings.
+func (n *node[K, V]) search(key K) (int, bool) {
+	lo, hi := 0, len(n.keys)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if cmp.Less(n.keys[m], key) {
+			lo = m + 1
+		} else {
+			hi = m
+		}
 	}
-	for i, c := range n.children {
-		if i == len(n.keys) || key < n.keys[i] {
-			ok, key2, n2 = c.insert(key, val, replace)
-			break
+	return lo, lo < len(n.keys) && !cmp.Less(key, n.keys[lo])
+}
+
+// childIndex returns the index of the child of internal node whose subtree covers a given key,
+// i.e. the number of separator keys less or equal to it.
+func (n *node[K, V]) childIndex(key K) int {
+	lo, hi := 0, len(n.keys)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if cmp.Less(key, n.keys[m]) {
+			hi = m
+		} else {
+			lo = m + 1
 		}
 	}
+	return lo
+}
+
+// insert puts a key-value pair to subtree and returns the change of number of pairs in it, which is negative
+// if multiple values are replaced with one, and a new right sibling node with its minimal key if n was split.
+func (n *node[K, V]) insert(t *BPTree[K, V], key K, val V, replace bool) (added int, key2 K, n2 *node[K, V]) {
+	if n.isLeaf() {
+		return n.insertToLeaf(t, key, val, replace)
+	}
+	added, key2, n2 = n.children[n.childIndex(key)].insert(t, key, val, replace)
+	n.count += added
 	if n2 != nil {
-		key2, n2 = n.insertToInternal(key2, n2)
+		key2, n2 = n.insertToInternal(t, key2, n2)
 	}
 	return
 }
 
-func (n *node[K, V]) insertToLeaf(key K, val V, replace bool) (ok bool, key2 K, n2 *node[K, V]) {
-	var pos int
-	for i, k := range n.keys {
-		if k > key {
-			break
+func (n *node[K, V]) insertToLeaf(t *BPTree[K, V], key K, val V, replace bool) (added int, key2 K, n2 *node[K, V]) {
+	pos, found := n.search(key)
+	if found && n.values == nil {
+		return 0, key2, n2
+	}
+	if found {
+		if _, ok := n.values[pos].(tombstone); ok {
+			n.values[pos] = val
+			t.tombstones--
+			n.count++
+			return 1, key2, n2
 		}
-		if k == key {
-			if replace {
-				n.values[i] = val
-				return false, key2, n2
+		if replace {
+			added = 1 - valueCount[V](n.values[pos])
+			n.values[pos] = val
+			n.count += added
+			return added, key2, n2
+		} else {
+			if c, ok := n.values[pos].(collision[V]); !ok {
+				t.counters.CollisionAllocs++
+				c = collision[V]{n.values[pos].(V), val}
+				n.values[pos] = c
 			} else {
-				if c, ok := n.values[i].(collision[V]); !ok {
-					c = collision[V]{n.values[i].(V), val}
-					n.values[i] = c
-				} else {
-					n.values[i] = append(c, val)
+				if len(c) == cap(c) {
+					t.counters.CollisionGrowths++
 				}
-				return true, key2, n2
+				n.values[pos] = append(c, val)
 			}
-		}
-		if k < key {
-			pos = i + 1
-			continue
+			n.count++
+			return 1, key2, n2
 		}
 	}
 	if len(n.keys) < cap(n.keys) {
 		n.keys = n.keys[:len(n.keys)+1]
-		n.values = n.values[:len(n.values)+1]
 		copy(n.keys[pos+1:], n.keys[pos:len(n.keys)-1])
-		copy(n.values[pos+1:], n.values[pos:len(n.values)-1])
 		n.keys[pos] = key
-		n.values[pos] = val
-		return true, key2, n2
+		if n.values != nil {
+			n.values = n.values[:len(n.values)+1]
+			copy(n.values[pos+1:], n.values[pos:len(n.values)-1])
+			n.values[pos] = val
+		}
+		n.count++
+		return 1, key2, n2
 	}
-	n2 = newLeafNode[K, V](cap(n.keys))
+	t.counters.Splits++
+	n2 = t.allocLeaf(cap(n.keys))
 	n2.right = n.right
 	if n.right != nil {
 		n.right.left = n2
@@ -393,40 +709,43 @@ func (n *node[K, V]) insertToLeaf(key K, val V, replace bool) (ok bool, key2 K,
 	n.right = n2
 	n2.left = n
 	n2.keys = n2.keys[:cap(n.keys)+1-n.bmin]
-	n2.values = n2.values[:cap(n.values)+1-n.bmin]
 	if pos < n.bmin {
 		copy(n2.keys, n.keys[n.bmin-1:])
-		copy(n2.values, n.values[n.bmin-1:])
 		n.keys = n.keys[:n.bmin]
-		n.values = n.values[:n.bmin]
 		copy(n.keys[pos+1:], n.keys[pos:n.bmin-1])
-		copy(n.values[pos+1:], n.values[pos:n.bmin-1])
 		n.keys[pos] = key
-		n.values[pos] = val
 	} else {
 		pos2 := pos - n.bmin
 		copy(n2.keys, n.keys[n.bmin:pos])
-		copy(n2.values, n.values[n.bmin:pos])
 		n2.keys[pos2] = key
-		n2.values[pos2] = val
 		copy(n2.keys[pos2+1:], n.keys[pos:])
-		copy(n2.values[pos2+1:], n.values[pos:])
 		n.keys = n.keys[:n.bmin]
-		n.values = n.values[:n.bmin]
 	}
-	trimValueSlice(n.values)
-	return true, n2.keys[0], n2
-}
-
-func (n *node[K, V]) insertToInternal(key K, child *node[K, V]) (key2 K, n2 *node[K, V]) {
-	var pos int
-	for i, k := range n.keys {
-		if k < key {
-			pos = i + 1
-			continue
+	// Only the slots moved to n2 are vacated, since n was full.
+	clear(n.keys[n.bmin:cap(n.keys)])
+	if n.values != nil {
+		n2.values = n2.values[:cap(n.values)+1-n.bmin]
+		if pos < n.bmin {
+			copy(n2.values, n.values[n.bmin-1:])
+			n.values = n.values[:n.bmin]
+			copy(n.values[pos+1:], n.values[pos:n.bmin-1])
+			n.values[pos] = val
+		} else {
+			pos2 := pos - n.bmin
+			copy(n2.values, n.values[n.bmin:pos])
+			n2.values[pos2] = val
+			copy(n2.values[pos2+1:], n.values[pos:])
+			n.values = n.values[:n.bmin]
 		}
-		break
+		clear(n.values[n.bmin:cap(n.values)])
 	}
+	n2.count = leafCount(n2)
+	n.count = n.count + 1 - n2.count
+	return 1, n2.keys[0], n2
+}
+
+func (n *node[K, V]) insertToInternal(t *BPTree[K, V], key K, child *node[K, V]) (key2 K, n2 *node[K, V]) {
+	pos, _ := n.search(key)
 	cpos := pos + 1
 	if len(n.children) < cap(n.children) {
 		n.keys = n.keys[:len(n.keys)+1]
@@ -437,7 +756,8 @@ func (n *node[K, V]) insertToInternal(key K, child *node[K, V]) (key2 K, n2 *nod
 		n.children[cpos] = child
 		return
 	}
-	n2 = newInternalNode[K, V](cap(n.children))
+	t.counters.Splits++
+	n2 = t.allocInternal(cap(n.children))
 	n2.right = n.right
 	if n.right != nil {
 		n.right.left = n2
@@ -475,99 +795,174 @@ func (n *node[K, V]) insertToInternal(key K, child *node[K, V]) (key2 K, n2 *nod
 		n.keys = n.keys[:n.bmin-1]
 		n.children = n.children[:n.bmin]
 	}
-	trimNodeSlice(n.children)
+	// Only the slots moved to n2 are vacated, since n was full.
+	clear(n.keys[n.bmin-1 : cap(n.keys)])
+	clear(n.children[n.bmin:cap(n.children)])
+	n2.count = childrenCount(n2)
+	n.count -= n2.count
 	return
 }
 
-func (n *node[K, V]) delete(key K, all bool, idx int) (val any, ok bool) {
+func (n *node[K, V]) delete(t *BPTree[K, V], key K, all bool, idx int) (val any, ok bool) {
 	if n.isLeaf() {
-		return n.deleteFromLeaf(key, all, idx)
-	}
-	var i int
-	var c *node[K, V]
-	for i, c = range n.children {
-		if i == len(n.keys) || key < n.keys[i] {
-			val, ok = c.delete(key, all, idx)
-			break
+		val, ok = n.deleteFromLeaf(t, key, all, idx)
+	} else {
+		i := n.childIndex(key)
+		c := n.children[i]
+		val, ok = c.delete(t, key, all, idx)
+		if ok {
+			n.balanceChild(t, i)
 		}
 	}
 	if ok {
-		if c.isLeaf() {
-			if len(c.values) < n.bmin {
-				n.balanceLeaf(i)
-			}
+		if all {
+			n.count -= len(val.(collision[V]))
 		} else {
-			if len(c.children) < n.bmin {
-				n.balanceInternal(i)
-			}
+			n.count--
 		}
 	}
 	return
 }
 
-func (n *node[K, V]) deleteFromLeaf(key K, all bool, idx int) (val any, ok bool) {
-	for i, k := range n.keys {
-		if k == key {
-			if all {
-				if c, ok := n.values[i].(collision[V]); !ok {
-					val = collision[V]{n.values[i].(V)}
-				} else {
-					val = c
-				}
+// pop removes the first value of the minimal key in subtree n, or the last value of the maximal key if last is true,
+// descending only into the subtree holding it. Subtree must have pairs.
+func (n *node[K, V]) pop(t *BPTree[K, V], last bool) (key K, val any) {
+	i, step := 0, 1
+	if last {
+		i, step = len(n.keys)-1, -1
+	}
+	if n.isLeaf() {
+		for n.values != nil && valueCount[V](n.values[i]) == 0 {
+			i += step
+		}
+		idx := 0
+		if last {
+			idx = -1
+		}
+		key = n.keys[i]
+		val, _ = n.deleteAt(t, i, false, idx)
+	} else {
+		if last {
+			i = len(n.children) - 1
+		}
+		// subtrees of only tombstones are skipped by their counts
+		for n.children[i].count == 0 {
+			i += step
+		}
+		key, val = n.children[i].pop(t, last)
+		n.balanceChild(t, i)
+	}
+	n.count--
+	return
+}
+
+// balanceChild restores the minimal size of the i-th child after a deletion from it.
+func (n *node[K, V]) balanceChild(t *BPTree[K, V], i int) {
+	if c := n.children[i]; c.isLeaf() {
+		if len(c.keys) < n.bmin {
+			n.balanceLeaf(t, i)
+		}
+	} else {
+		if len(c.children) < n.bmin {
+			n.balanceInternal(t, i)
+		}
+	}
+}
+
+func (n *node[K, V]) deleteFromLeaf(t *BPTree[K, V], key K, all bool, idx int) (val any, ok bool) {
+	i, found := n.search(key)
+	if !found {
+		return
+	}
+	return n.deleteAt(t, i, all, idx)
+}
+
+// deleteAt is deleteFromLeaf for the i-th key of leaf.
+func (n *node[K, V]) deleteAt(t *BPTree[K, V], i int, all bool, idx int) (val any, ok bool) {
+	if n.values == nil {
+		var zero V
+		n.deleteSlot(i)
+		return zero, true
+	}
+	if _, ok := n.values[i].(tombstone); ok {
+		return nil, false
+	}
+	if all {
+		if c, ok := n.values[i].(collision[V]); !ok {
+			val = collision[V]{n.values[i].(V)}
+		} else {
+			val = c
+		}
+	} else {
+		if c, ok := n.values[i].(collision[V]); !ok {
+			if idx > 0 {
+				return nil, false
+			}
+			val = n.values[i]
+		} else {
+			if idx >= len(c) {
+				return nil, false
+			}
+			var zero V
+			if idx < 0 {
+				val = c[len(c)-1]
+				c[len(c)-1] = zero
+				n.values[i] = c[:len(c)-1]
 			} else {
-				if c, ok := n.values[i].(collision[V]); !ok {
-					if idx > 0 {
-						return nil, false
-					}
-					val = n.values[i]
-				} else {
-					if idx >= len(c) {
-						return nil, false
-					}
-					var zero V
-					if idx < 0 {
-						val = c[len(c)-1]
-						c[len(c)-1] = zero
-						n.values[i] = c[:len(c)-1]
-					} else {
-						val = c[idx]
-						copy(c[idx:], c[idx+1:])
-						c[len(c)-1] = zero
-						n.values[i] = c[:len(c)-1]
-					}
-					if len(n.values[i].(collision[V])) != 0 {
-						return val, true
-					}
-				}
+				val = c[idx]
+				copy(c[idx:], c[idx+1:])
+				c[len(c)-1] = zero
+				n.values[i] = c[:len(c)-1]
+			}
+			if len(n.values[i].(collision[V])) != 0 {
+				return val, true
 			}
-			ok = true
-			copy(n.keys[i:len(n.keys)-1], n.keys[i+1:len(n.keys)])
-			copy(n.values[i:len(n.values)-1], n.values[i+1:len(n.values)])
-			n.keys = n.keys[:len(n.keys)-1]
-			n.values[len(n.values)-1] = nil
-			n.values = n.values[:len(n.values)-1]
-			return
 		}
 	}
+	ok = true
+	if t.lazyDeletion {
+		n.values[i] = tombstone{}
+		t.tombstones++
+		return
+	}
+	n.deleteSlot(i)
 	return
 }
 
-func (n *node[K, V]) balanceLeaf(i int) {
+// deleteSlot removes the i-th key of leaf with its value slot, if leaf has them.
+func (n *node[K, V]) deleteSlot(i int) {
+	copy(n.keys[i:len(n.keys)-1], n.keys[i+1:len(n.keys)])
+	clear(n.keys[len(n.keys)-1:])
+	n.keys = n.keys[:len(n.keys)-1]
+	if n.values != nil {
+		copy(n.values[i:len(n.values)-1], n.values[i+1:len(n.values)])
+		n.values[len(n.values)-1] = nil
+		n.values = n.values[:len(n.values)-1]
+	}
+}
+
+func (n *node[K, V]) balanceLeaf(t *BPTree[K, V], i int) {
 	c := n.children[i]
-	if i != 0 && len(n.children[i-1].values) > n.bmin {
+	if i != 0 && len(n.children[i-1].keys) > n.bmin {
+		t.counters.Borrows++
 		n.keys[i-1] = c.takeFromLeftSiblingLeaf(n.children[i-1])
 		return
 	}
-	if i != len(n.children)-1 && len(n.children[i+1].values) > n.bmin {
+	if i != len(n.children)-1 && len(n.children[i+1].keys) > n.bmin {
+		t.counters.Borrows++
 		n.keys[i] = c.takeFromRightSiblingLeaf(n.children[i+1])
 		return
 	}
-	if i != 0 && (i == len(n.children)-1 || len(n.children[i-1].values) < len(n.children[i+1].values)) {
+	t.counters.Merges++
+	if i != 0 && (i == len(n.children)-1 || len(n.children[i-1].keys) < len(n.children[i+1].keys)) {
 		mergeLeafs(n.children[i-1], c)
 		n.deleteChild(i)
+		t.freeNode(c)
 	} else {
-		mergeLeafs(c, n.children[i+1])
+		r := n.children[i+1]
+		mergeLeafs(c, r)
 		n.deleteChild(i + 1)
+		t.freeNode(r)
 	}
 }
 
@@ -575,12 +970,19 @@ func (n *node[K, V]) takeFromLeftSiblingLeaf(n2 *node[K, V]) K {
 	n.keys = n.keys[:len(n.keys)+1]
 	copy(n.keys[1:], n.keys[:len(n.keys)-1])
 	n.keys[0] = n2.keys[len(n2.keys)-1]
+	clear(n2.keys[len(n2.keys)-1:])
 	n2.keys = n2.keys[:len(n2.keys)-1]
-	n.values = n.values[:len(n.values)+1]
-	copy(n.values[1:], n.values[:len(n.values)-1])
-	n.values[0] = n2.values[len(n2.values)-1]
-	n2.values[len(n2.values)-1] = nil
-	n2.values = n2.values[:len(n2.values)-1]
+	moved := 1
+	if n.values != nil {
+		n.values = n.values[:len(n.values)+1]
+		copy(n.values[1:], n.values[:len(n.values)-1])
+		n.values[0] = n2.values[len(n2.values)-1]
+		n2.values[len(n2.values)-1] = nil
+		n2.values = n2.values[:len(n2.values)-1]
+		moved = valueCount[V](n.values[0])
+	}
+	n.count += moved
+	n2.count -= moved
 	return n.keys[0]
 }
 
@@ -588,31 +990,44 @@ func (n *node[K, V]) takeFromRightSiblingLeaf(n2 *node[K, V]) K {
 	n.keys = n.keys[:len(n.keys)+1]
 	n.keys[len(n.keys)-1] = n2.keys[0]
 	copy(n2.keys[:len(n2.keys)-1], n2.keys[1:len(n2.keys)])
+	clear(n2.keys[len(n2.keys)-1:])
 	n2.keys = n2.keys[:len(n2.keys)-1]
-	n.values = n.values[:len(n.values)+1]
-	n.values[len(n.values)-1] = n2.values[0]
-	copy(n2.values[:len(n2.values)-1], n2.values[1:len(n2.values)])
-	n2.values[len(n2.values)-1] = nil
-	n2.values = n2.values[:len(n2.values)-1]
+	moved := 1
+	if n.values != nil {
+		n.values = n.values[:len(n.values)+1]
+		n.values[len(n.values)-1] = n2.values[0]
+		copy(n2.values[:len(n2.values)-1], n2.values[1:len(n2.values)])
+		n2.values[len(n2.values)-1] = nil
+		n2.values = n2.values[:len(n2.values)-1]
+		moved = valueCount[V](n.values[len(n.values)-1])
+	}
+	n.count += moved
+	n2.count -= moved
 	return n2.keys[0]
 }
 
-func (n *node[K, V]) balanceInternal(i int) {
+func (n *node[K, V]) balanceInternal(t *BPTree[K, V], i int) {
 	c := n.children[i]
 	if i != 0 && len(n.children[i-1].children) > n.bmin {
+		t.counters.Borrows++
 		n.keys[i-1] = c.takeFromLeftSiblingInternal(n.children[i-1], n.keys[i-1])
 		return
 	}
 	if i != len(n.children)-1 && len(n.children[i+1].children) > n.bmin {
+		t.counters.Borrows++
 		n.keys[i] = c.takeFromRightSiblingInternal(n.children[i+1], n.keys[i])
 		return
 	}
+	t.counters.Merges++
 	if i != 0 && (i == len(n.children)-1 || len(n.children[i-1].children) < len(n.children[i+1].children)) {
 		mergeInternal(n.children[i-1], c, n.keys[i-1])
 		n.deleteChild(i)
+		t.freeNode(c)
 	} else {
-		mergeInternal(c, n.children[i+1], n.keys[i])
+		r := n.children[i+1]
+		mergeInternal(c, r, n.keys[i])
 		n.deleteChild(i + 1)
+		t.freeNode(r)
 	}
 }
 
@@ -621,12 +1036,15 @@ func (n *node[K, V]) takeFromLeftSiblingInternal(n2 *node[K, V], key K) K {
 	copy(n.keys[1:], n.keys[:len(n.keys)-1])
 	mkey := n2.keys[len(n2.keys)-1]
 	n.keys[0] = key
+	clear(n2.keys[len(n2.keys)-1:])
 	n2.keys = n2.keys[:len(n2.keys)-1]
 	n.children = n.children[:len(n.children)+1]
 	copy(n.children[1:], n.children[:len(n.children)-1])
 	n.children[0] = n2.children[len(n2.children)-1]
 	n2.children[len(n2.children)-1] = nil
 	n2.children = n2.children[:len(n2.children)-1]
+	n.count += n.children[0].count
+	n2.count -= n.children[0].count
 	return mkey
 }
 
@@ -635,17 +1053,21 @@ func (n *node[K, V]) takeFromRightSiblingInternal(n2 *node[K, V], key K) K {
 	n.keys[len(n.keys)-1] = key
 	mkey := n2.keys[0]
 	copy(n2.keys[:len(n2.keys)-1], n2.keys[1:len(n2.keys)])
+	clear(n2.keys[len(n2.keys)-1:])
 	n2.keys = n2.keys[:len(n2.keys)-1]
 	n.children = n.children[:len(n.children)+1]
 	n.children[len(n.children)-1] = n2.children[0]
 	copy(n2.children[:len(n2.children)-1], n2.children[1:len(n2.children)])
 	n2.children[len(n2.children)-1] = nil
 	n2.children = n2.children[:len(n2.children)-1]
+	n.count += n.children[len(n.children)-1].count
+	n2.count -= n.children[len(n.children)-1].count
 	return mkey
 }
 
 func (n *node[K, V]) deleteChild(i int) {
 	copy(n.keys[i-1:len(n.keys)-1], n.keys[i:len(n.keys)])
+	clear(n.keys[len(n.keys)-1:])
 	n.keys = n.keys[:len(n.keys)-1]
 	copy(n.children[i:len(n.children)-1], n.children[i+1:len(n.children)])
 	n.children[len(n.children)-1] = nil
@@ -660,8 +1082,11 @@ func mergeLeafs[K Key, V any](l, r *node[K, V]) {
 	llen, rlen := len(l.keys), len(r.keys)
 	l.keys = l.keys[:llen+rlen]
 	copy(l.keys[llen:], r.keys)
-	l.values = l.values[:llen+rlen]
-	copy(l.values[llen:], r.values)
+	if l.values != nil {
+		l.values = l.values[:llen+rlen]
+		copy(l.values[llen:], r.values)
+	}
+	l.count += r.count
 }
 
 func mergeInternal[K Key, V any](l, r *node[K, V], key K) {
@@ -675,26 +1100,37 @@ func mergeInternal[K Key, V any](l, r *node[K, V], key K) {
 	copy(l.keys[nlkeys+1:], r.keys)
 	l.children = l.children[:len(l.keys)+1]
 	copy(l.children[nlch:], r.children)
+	l.count += r.count
 }
 
-func trimNodeSlice[K Key, V any](s []*node[K, V]) {
-	s = s[len(s):cap(s)]
-	if len(s) == 0 {
-		return
-	}
-	s[0] = nil
-	for i := 1; i < len(s); i *= 2 {
-		copy(s[i:], s[:i])
+// valueCount returns a number of key-value pairs in a value slot of leaf.
+func valueCount[V any](v any) int {
+	switch v := v.(type) {
+	case tombstone:
+		return 0
+	case collision[V]:
+		return len(v)
 	}
+	return 1
 }
 
-func trimValueSlice(s []any) {
-	s = s[len(s):cap(s)]
-	if len(s) == 0 {
-		return
+// leafCount returns a number of key-value pairs in leaf n.
+func leafCount[K Key, V any](n *node[K, V]) int {
+	if n.values == nil {
+		return len(n.keys)
+	}
+	var count int
+	for _, v := range n.values {
+		count += valueCount[V](v)
 	}
-	s[0] = nil
-	for i := 1; i < len(s); i *= 2 {
-		copy(s[i:], s[:i])
+	return count
+}
+
+// childrenCount returns a number of key-value pairs in subtrees of children of internal node n.
+func childrenCount[K Key, V any](n *node[K, V]) int {
+	var count int
+	for _, c := range n.children {
+		count += c.count
 	}
+	return count
 }
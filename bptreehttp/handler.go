@@ -0,0 +1,299 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bptreehttp provides an HTTP handler exposing a BPTree as a small ordered key-value service.
+//
+// Endpoints, relative to the handler mount point:
+//
+//	GET    /{key}                                 value of a key as JSON
+//	PUT    /{key}                                 replace value of a key with the JSON body
+//	DELETE /{key}                                 remove a key with all its values
+//	GET    /?from=&to=&limit=&token=              page of key-value pairs from interval [from; to)
+//
+// Range responses contain a "next" token if there are more pairs; pass it as the token parameter
+// to get the next page.
+package bptreehttp
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dmitrydikun/bptree"
+)
+
+const (
+	// DefaultLimit is a number of pairs returned in a range page if limit is not specified.
+	DefaultLimit = 100
+	// MaxLimit is a maximum number of pairs returned in a range page.
+	MaxLimit = 10000
+)
+
+// Entry is a key-value pair of a range response.
+type Entry[K bptree.Key, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// MarshalJSON encodes the entry as an object with "key" and "value" fields. NaN and infinite float keys,
+// which JSON numbers can not represent, are encoded as strings "NaN", "+Inf" and "-Inf" accepted by ParseKey.
+func (e Entry[K, V]) MarshalJSON() ([]byte, error) {
+	var key any = e.Key
+	if v := reflect.ValueOf(e.Key); v.CanFloat() && (math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)) {
+		key = strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	return json.Marshal(struct {
+		Key   any `json:"key"`
+		Value V   `json:"value"`
+	}{key, e.Value})
+}
+
+// UnmarshalJSON decodes an entry encoded by MarshalJSON.
+func (e *Entry[K, V]) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Key   json.RawMessage `json:"key"`
+		Value V               `json:"value"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	e.Value = raw.Value
+	if raw.Key == nil {
+		return nil
+	}
+	var s string
+	if reflect.ValueOf(e.Key).CanFloat() && json.Unmarshal(raw.Key, &s) == nil {
+		k, err := ParseKey[K](s)
+		e.Key = k
+		return err
+	}
+	return json.Unmarshal(raw.Key, &e.Key)
+}
+
+// RangeResponse is a body of a range response.
+type RangeResponse[K bptree.Key, V any] struct {
+	Entries []Entry[K, V] `json:"entries"`
+	Next    string        `json:"next,omitempty"`
+}
+
+// Handler is an http.Handler serving a tree. Access to the tree is serialized by the handler,
+// so the tree must not be used directly while the handler is serving requests.
+type Handler[K bptree.Key, V any] struct {
+	mu       sync.RWMutex
+	t        *bptree.BPTree[K, V]
+	parseKey func(string) (K, error)
+}
+
+// NewHandler returns a Handler for a given tree. ParseKey converts path segments and query parameters
+// to keys; if nil, strings are used as is and numbers are parsed with strconv.
+func NewHandler[K bptree.Key, V any](t *bptree.BPTree[K, V], parseKey func(string) (K, error)) *Handler[K, V] {
+	if parseKey == nil {
+		parseKey = ParseKey[K]
+	}
+	return &Handler[K, V]{t: t, parseKey: parseKey}
+}
+
+// ParseKey is the default key parser.
+func ParseKey[K bptree.Key](s string) (K, error) {
+	var k K
+	v := reflect.ValueOf(&k).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return k, err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return k, err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return k, err
+		}
+		v.SetFloat(f)
+	}
+	return k, nil
+}
+
+// pageToken is encoded as the uvarint skip followed by the key in the OrderedKeyCodec form,
+// which unlike JSON can represent every key including NaN and ±Inf.
+type pageToken[K bptree.Key] struct {
+	Key  K
+	Skip int
+}
+
+func (h *Handler[K, V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.serveRange(w, r)
+		return
+	}
+	key, err := h.parseKey(path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid key: %s", err), http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.mu.RLock()
+		v, ok := h.t.Find(key)
+		h.mu.RUnlock()
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, v)
+	case http.MethodPut:
+		var v V
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid value: %s", err), http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		h.t.Insert(key, v)
+		h.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		h.mu.Lock()
+		_, ok := h.t.DeleteAll(key)
+		h.mu.Unlock()
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler[K, V]) serveRange(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to *K
+	for _, p := range []struct {
+		name string
+		key  **K
+	}{{"from", &from}, {"to", &to}} {
+		if s := q.Get(p.name); s != "" {
+			k, err := h.parseKey(s)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", p.name, err), http.StatusBadRequest)
+				return
+			}
+			*p.key = &k
+		}
+	}
+	limit := DefaultLimit
+	if s := q.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(l, MaxLimit)
+	}
+	skip := 0
+	if s := q.Get("token"); s != "" {
+		token, err := decodeToken[K](s)
+		if err != nil {
+			http.Error(w, "invalid token", http.StatusBadRequest)
+			return
+		}
+		from, skip = &token.Key, token.Skip
+	}
+	resp := RangeResponse[K, V]{Entries: []Entry[K, V]{}}
+	var last K
+	var run int
+	var next *pageToken[K]
+	h.mu.RLock()
+	i := h.t.Iterator(from, to)
+	for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+		if run > 0 && cmp.Compare(kv.Key, last) == 0 {
+			run++
+		} else {
+			last, run = kv.Key, 1
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if len(resp.Entries) == limit {
+			next = &pageToken[K]{Key: kv.Key, Skip: run - 1}
+			break
+		}
+		resp.Entries = append(resp.Entries, Entry[K, V]{Key: kv.Key, Value: kv.Value.(V)})
+	}
+	h.mu.RUnlock()
+	if next != nil {
+		s, err := encodeToken(*next)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Next = s
+	}
+	writeJSON(w, resp)
+}
+
+func encodeToken[K bptree.Key](t pageToken[K]) (string, error) {
+	k, err := bptree.OrderedKeyCodec[K]{}.Encode(t.Key)
+	if err != nil {
+		return "", err
+	}
+	b := append(binary.AppendUvarint(nil, uint64(t.Skip)), k...)
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeToken[K bptree.Key](s string) (t pageToken[K], err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, err
+	}
+	skip, n := binary.Uvarint(b)
+	if n <= 0 || skip > math.MaxInt32 {
+		return t, errors.New("invalid skip")
+	}
+	t.Skip = int(skip)
+	t.Key, err = bptree.OrderedKeyCodec[K]{}.Decode(b[n:])
+	return t, err
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptreehttp
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dmitrydikun/bptree"
+)
+
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(T *testing.T) {
+	t := bptree.NewBPTree[int, string](4)
+	h := NewHandler(t, nil)
+	for i := 0; i < 10; i++ {
+		if w := do(h, http.MethodPut, fmt.Sprintf("/%d", i), fmt.Sprintf("%q", fmt.Sprint("v", i))); w.Code != http.StatusNoContent {
+			T.Fatalf("put: status %d", w.Code)
+		}
+	}
+	w := do(h, http.MethodGet, "/3", "")
+	var v string
+	if err := json.NewDecoder(w.Body).Decode(&v); w.Code != http.StatusOK || err != nil || v != "v3" {
+		T.Fatalf("get: status %d, value %q, error %v", w.Code, v, err)
+	}
+	if w := do(h, http.MethodGet, "/x", ""); w.Code != http.StatusBadRequest {
+		T.Fatalf("get invalid key: status %d", w.Code)
+	}
+	if w := do(h, http.MethodDelete, "/3", ""); w.Code != http.StatusNoContent {
+		T.Fatalf("delete: status %d", w.Code)
+	}
+	if w := do(h, http.MethodGet, "/3", ""); w.Code != http.StatusNotFound {
+		T.Fatalf("get deleted: status %d", w.Code)
+	}
+	if w := do(h, http.MethodDelete, "/3", ""); w.Code != http.StatusNotFound {
+		T.Fatalf("delete deleted: status %d", w.Code)
+	}
+	if w := do(h, http.MethodPost, "/3", ""); w.Code != http.StatusMethodNotAllowed {
+		T.Fatalf("post: status %d", w.Code)
+	}
+	t.Append(5, "v5a")
+	t.Append(5, "v5b")
+	var all []Entry[int, string]
+	target := "/?from=1&to=9&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			T.Fatal("too many pages")
+		}
+		w := do(h, http.MethodGet, target, "")
+		var resp RangeResponse[int, string]
+		if err := json.NewDecoder(w.Body).Decode(&resp); w.Code != http.StatusOK || err != nil {
+			T.Fatalf("range: status %d, error %v", w.Code, err)
+		}
+		if len(resp.Entries) > 2 {
+			T.Fatalf("range: page size %d > limit", len(resp.Entries))
+		}
+		all = append(all, resp.Entries...)
+		if resp.Next == "" {
+			break
+		}
+		target = "/?to=9&limit=2&token=" + url.QueryEscape(resp.Next)
+	}
+	needed := []string{"v1", "v2", "v4", "v5", "v5a", "v5b", "v6", "v7", "v8"}
+	if len(all) != len(needed) {
+		T.Fatalf("range: %v, needed values %v", all, needed)
+	}
+	for i, e := range all {
+		if e.Value != needed[i] {
+			T.Fatalf("range: %v, needed values %v", all, needed)
+		}
+	}
+}
+
+func TestHandlerNonFiniteKeys(T *testing.T) {
+	t := bptree.NewBPTree[float64, string](4)
+	h := NewHandler(t, nil)
+	t.Append(math.NaN(), "nan1")
+	t.Append(math.NaN(), "nan2")
+	t.Append(math.NaN(), "nan3")
+	t.Insert(math.Inf(-1), "-inf")
+	t.Insert(1, "v1")
+	t.Insert(math.Inf(1), "+inf")
+	var all []Entry[float64, string]
+	target := "/?limit=1"
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			T.Fatal("too many pages")
+		}
+		w := do(h, http.MethodGet, target, "")
+		var resp RangeResponse[float64, string]
+		if err := json.NewDecoder(w.Body).Decode(&resp); w.Code != http.StatusOK || err != nil {
+			T.Fatalf("range: status %d, error %v", w.Code, err)
+		}
+		all = append(all, resp.Entries...)
+		if resp.Next == "" {
+			break
+		}
+		target = "/?limit=1&token=" + url.QueryEscape(resp.Next)
+	}
+	nan, inf := math.NaN(), math.Inf(1)
+	needed := []Entry[float64, string]{{nan, "nan1"}, {nan, "nan2"}, {nan, "nan3"}, {-inf, "-inf"}, {1, "v1"}, {inf, "+inf"}}
+	if !slices.EqualFunc(all, needed, func(a, b Entry[float64, string]) bool {
+		return cmp.Compare(a.Key, b.Key) == 0 && a.Value == b.Value
+	}) {
+		T.Fatalf("range: %v, needed %v", all, needed)
+	}
+	if _, err := decodeToken[float64]("AAE"); err == nil {
+		T.Fatal("decode token with truncated key: no error")
+	}
+}
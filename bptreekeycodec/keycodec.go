@@ -0,0 +1,199 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bptreekeycodec encodes values into byte strings whose lexicographic order (bytes.Compare)
+// matches the natural order of values, and decodes them back. Encodings of several values appended
+// one after another form a tuple, ordered by its first value, then by the second one, and so on,
+// which makes composite []byte or string keys of a BPTree.
+//
+// Append functions append an encoding to a slice and return the extended slice. Decode functions
+// decode a value from the beginning of a slice and return it with the rest of the slice, so
+// a tuple is decoded by calling them in the order its values were appended.
+package bptreekeycodec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dmitrydikun/bptree/internal/keybits"
+)
+
+// ErrInvalidEncoding is returned when bytes are not a valid encoding of a requested type.
+var ErrInvalidEncoding = errors.New("invalid key encoding")
+
+// AppendInt appends v as 8 bytes big-endian with the sign bit flipped.
+func AppendInt(b []byte, v int64) []byte {
+	return AppendUint(b, keybits.Int(uint64(v), 8))
+}
+
+// DecodeInt decodes a value appended by AppendInt.
+func DecodeInt(b []byte) (int64, []byte, error) {
+	u, rest, err := DecodeUint(b)
+	return int64(keybits.Int(u, 8)), rest, err
+}
+
+// AppendUint appends v as 8 bytes big-endian.
+func AppendUint(b []byte, v uint64) []byte {
+	for i := 7; i >= 0; i-- {
+		b = append(b, byte(v>>(i*8)))
+	}
+	return b
+}
+
+// DecodeUint decodes a value appended by AppendUint.
+func DecodeUint(b []byte) (uint64, []byte, error) {
+	if len(b) < 8 {
+		return 0, b, ErrInvalidEncoding
+	}
+	var v uint64
+	for _, c := range b[:8] {
+		v = v<<8 | uint64(c)
+	}
+	return v, b[8:], nil
+}
+
+// AppendFloat appends IEEE 754 bits of v transformed to compare as unsigned integers, as 8 bytes
+// big-endian. NaN is ordered before all other values, like in cmp.Compare, and -0 is equal to 0.
+func AppendFloat(b []byte, v float64) []byte {
+	return AppendUint(b, keybits.Float64(v))
+}
+
+// DecodeFloat decodes a value appended by AppendFloat.
+func DecodeFloat(b []byte) (float64, []byte, error) {
+	bits, rest, err := DecodeUint(b)
+	if err != nil {
+		return 0, b, err
+	}
+	return keybits.FromFloat64(bits), rest, nil
+}
+
+// AppendBytes appends v with every zero byte escaped as 0x00 0xFF, followed by terminator 0x00 0x01,
+// so a shorter value is ordered before values it is a prefix of, and the next value of a tuple
+// does not affect the order.
+func AppendBytes(b []byte, v []byte) []byte {
+	for {
+		i := bytes.IndexByte(v, 0)
+		if i < 0 {
+			break
+		}
+		b = append(b, v[:i+1]...)
+		b = append(b, 0xFF)
+		v = v[i+1:]
+	}
+	b = append(b, v...)
+	return append(b, 0x00, 0x01)
+}
+
+// DecodeBytes decodes a value appended by AppendBytes. The returned slice is newly allocated.
+func DecodeBytes(b []byte) ([]byte, []byte, error) {
+	var v []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] != 0 {
+			continue
+		}
+		if i+1 == len(b) {
+			break
+		}
+		switch b[i+1] {
+		case 0x01:
+			return append(v, b[:i]...), b[i+2:], nil
+		case 0xFF:
+			v = append(v, b[:i+1]...)
+			b = b[i+2:]
+			i = -1
+		default:
+			return nil, b, ErrInvalidEncoding
+		}
+	}
+	return nil, b, ErrInvalidEncoding
+}
+
+// AppendString is like AppendBytes for a string.
+func AppendString(b []byte, v string) []byte {
+	return AppendBytes(b, []byte(v))
+}
+
+// DecodeString decodes a value appended by AppendString.
+func DecodeString(b []byte) (string, []byte, error) {
+	v, rest, err := DecodeBytes(b)
+	return string(v), rest, err
+}
+
+// AppendTime appends v as its Unix time in seconds, encoded like by AppendInt, followed by nanoseconds
+// as 4 bytes big-endian. Time zone is not stored.
+func AppendTime(b []byte, v time.Time) []byte {
+	b = AppendInt(b, v.Unix())
+	nsec := uint32(v.Nanosecond())
+	return append(b, byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec))
+}
+
+// DecodeTime decodes a value appended by AppendTime, in UTC.
+func DecodeTime(b []byte) (time.Time, []byte, error) {
+	sec, rest, err := DecodeInt(b)
+	if err != nil || len(rest) < 4 {
+		return time.Time{}, b, ErrInvalidEncoding
+	}
+	nsec := uint32(rest[0])<<24 | uint32(rest[1])<<16 | uint32(rest[2])<<8 | uint32(rest[3])
+	if nsec >= 1e9 {
+		return time.Time{}, b, ErrInvalidEncoding
+	}
+	return time.Unix(sec, int64(nsec)).UTC(), rest[4:], nil
+}
+
+// AppendTuple appends values one after another: signed integers with AppendInt, unsigned ones with AppendUint,
+// floats with AppendFloat, strings, byte slices and times with AppendString, AppendBytes and AppendTime.
+// Values of other types are reported as an error.
+func AppendTuple(b []byte, values ...any) ([]byte, error) {
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			b = AppendInt(b, int64(v))
+		case int8:
+			b = AppendInt(b, int64(v))
+		case int16:
+			b = AppendInt(b, int64(v))
+		case int32:
+			b = AppendInt(b, int64(v))
+		case int64:
+			b = AppendInt(b, v)
+		case uint:
+			b = AppendUint(b, uint64(v))
+		case uint8:
+			b = AppendUint(b, uint64(v))
+		case uint16:
+			b = AppendUint(b, uint64(v))
+		case uint32:
+			b = AppendUint(b, uint64(v))
+		case uint64:
+			b = AppendUint(b, v)
+		case uintptr:
+			b = AppendUint(b, uint64(v))
+		case float32:
+			b = AppendFloat(b, float64(v))
+		case float64:
+			b = AppendFloat(b, v)
+		case string:
+			b = AppendString(b, v)
+		case []byte:
+			b = AppendBytes(b, v)
+		case time.Time:
+			b = AppendTime(b, v)
+		default:
+			return b, fmt.Errorf("unsupported tuple value type %T", v)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptreekeycodec
+
+import (
+	"bytes"
+	"cmp"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkOrder[E any](T *testing.T, values []E, compare func(a, b E) int, encode func([]byte, E) []byte,
+	decode func([]byte) (E, []byte, error), equal func(a, b E) bool) {
+	for _, a := range values {
+		ea := encode([]byte{0xAB}, a)[1:]
+		d, rest, err := decode(append(ea, 0xCD))
+		if err != nil || !equal(d, a) || !bytes.Equal(rest, []byte{0xCD}) {
+			T.Errorf("decode of %v: (%v, %x, %v)", a, d, rest, err)
+		}
+		for _, b := range values {
+			eb := encode(nil, b)
+			if c := bytes.Compare(ea, eb); c != compare(a, b) {
+				T.Errorf("%v vs %v: encodings compare as %d, needed %d", a, b, c, compare(a, b))
+			}
+		}
+	}
+}
+
+func TestInt(T *testing.T) {
+	values := []int64{math.MinInt64, math.MinInt64 + 1, -1 << 32, -256, -1, 0, 1, 255, 256, 1 << 32, math.MaxInt64}
+	checkOrder(T, values, cmp.Compare[int64], AppendInt, DecodeInt, func(a, b int64) bool { return a == b })
+}
+
+func TestUint(T *testing.T) {
+	values := []uint64{0, 1, 255, 256, 1 << 32, math.MaxUint64 - 1, math.MaxUint64}
+	checkOrder(T, values, cmp.Compare[uint64], AppendUint, DecodeUint, func(a, b uint64) bool { return a == b })
+}
+
+func TestFloat(T *testing.T) {
+	values := []float64{math.NaN(), math.Inf(-1), -math.MaxFloat64, -1.5, -math.SmallestNonzeroFloat64,
+		math.Copysign(0, -1), 0, math.SmallestNonzeroFloat64, 1, 1.5, math.MaxFloat64, math.Inf(1)}
+	checkOrder(T, values, cmp.Compare[float64], AppendFloat, DecodeFloat, func(a, b float64) bool {
+		return a == b || a != a && b != b
+	})
+}
+
+func TestString(T *testing.T) {
+	values := []string{"", "\x00", "\x00\x00", "\x00\x01", "\x00\xFF", "\x01", "a", "a\x00", "a\x00b", "ab", "b", "\xFF"}
+	checkOrder(T, values, strings.Compare, AppendString, DecodeString, func(a, b string) bool { return a == b })
+	for _, b := range [][]byte{nil, {'a'}, {'a', 0}, {0, 2}} {
+		if _, _, err := DecodeString(b); err != ErrInvalidEncoding {
+			T.Errorf("decode of %x: %v", b, err)
+		}
+	}
+}
+
+func TestTime(T *testing.T) {
+	base := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	values := []time.Time{time.Unix(-1<<40, 0).UTC(), time.Unix(0, 0).UTC(), base, base.Add(1), base.Add(time.Second - 1),
+		base.Add(time.Second), time.Unix(1<<40, 999999999).UTC()}
+	checkOrder(T, values, time.Time.Compare, AppendTime, DecodeTime, time.Time.Equal)
+}
+
+func TestTuple(T *testing.T) {
+	tuples := [][]any{
+		{"a", int32(-1), 2.5},
+		{"a", int32(-1), 3.0},
+		{"a", int32(0), 0.0},
+		{"a\x00", int32(-5), 0.0},
+		{"ab", int32(-5), 0.0},
+	}
+	var prev []byte
+	for i, t := range tuples {
+		b, err := AppendTuple(nil, t...)
+		if err != nil {
+			T.Fatal(err)
+		}
+		if i > 0 && bytes.Compare(prev, b) >= 0 {
+			T.Errorf("tuple %v is not ordered after %v", t, tuples[i-1])
+		}
+		prev = b
+		s, b, err := DecodeString(b)
+		i32, b, err2 := DecodeInt(b)
+		f, b, err3 := DecodeFloat(b)
+		if err != nil || err2 != nil || err3 != nil || len(b) != 0 ||
+			s != t[0] || int32(i32) != t[1] || f != t[2] {
+			T.Errorf("decode of %v: (%q, %d, %g), %x left", t, s, i32, f, b)
+		}
+	}
+	if _, err := AppendTuple(nil, struct{}{}); err == nil {
+		T.Error("unsupported type is encoded")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bptreepq provides a priority queue backed by a BPTree. Items of equal priority are popped
+// in the order they were pushed, and the queue can be iterated in priority order and merged.
+package bptreepq
+
+import (
+	"github.com/dmitrydikun/bptree"
+)
+
+// Queue is a double-ended priority queue of values with priorities of type P. Not thread-safe.
+type Queue[P bptree.Key, V any] struct {
+	t *bptree.BPTree[P, V]
+}
+
+// New returns a new empty Queue.
+func New[P bptree.Key, V any]() *Queue[P, V] {
+	return &Queue[P, V]{t: bptree.NewBPTree[P, V](0)}
+}
+
+// Len returns a number of items in queue.
+func (q *Queue[P, V]) Len() int {
+	return q.t.Size()
+}
+
+// Push adds a value with a given priority.
+func (q *Queue[P, V]) Push(p P, v V) {
+	q.t.Append(p, v)
+}
+
+// PeekMin returns (priority, value, true) of the item with the minimal priority pushed first,
+// or (zero, zero, false) if queue is empty.
+func (q *Queue[P, V]) PeekMin() (P, V, bool) {
+	return q.t.Cursor().First()
+}
+
+// PeekMax returns (priority, value, true) of the item with the maximal priority pushed first,
+// or (zero, zero, false) if queue is empty.
+func (q *Queue[P, V]) PeekMax() (p P, v V, ok bool) {
+	if p, _, ok = q.t.Cursor().Last(); ok {
+		v, _ = q.t.Find(p)
+	}
+	return p, v, ok
+}
+
+// PopMin removes and returns the item returned by PeekMin.
+func (q *Queue[P, V]) PopMin() (p P, v V, ok bool) {
+	if p, _, ok = q.t.Cursor().First(); ok {
+		v, _ = q.t.DeleteOne(p, 0)
+	}
+	return p, v, ok
+}
+
+// PopMax removes and returns the item returned by PeekMax.
+func (q *Queue[P, V]) PopMax() (p P, v V, ok bool) {
+	if p, _, ok = q.t.Cursor().Last(); ok {
+		v, _ = q.t.DeleteOne(p, 0)
+	}
+	return p, v, ok
+}
+
+// Scan calls f for items in priority order, items of equal priority in push order, until f returns false.
+// Queue must not be modified by f.
+func (q *Queue[P, V]) Scan(f func(p P, v V) bool) {
+	c := q.t.Cursor()
+	for p, v, ok := c.First(); ok && f(p, v); p, v, ok = c.Next() {
+	}
+}
+
+// Merge moves all items of other to q, as if they were pushed after items of q in the order
+// they were pushed to other. Other becomes empty.
+func (q *Queue[P, V]) Merge(other *Queue[P, V]) {
+	other.Scan(func(p P, v V) bool {
+		q.t.Append(p, v)
+		return true
+	})
+	other.t.Clear()
+}
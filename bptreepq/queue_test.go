@@ -0,0 +1,68 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptreepq
+
+import (
+	"testing"
+)
+
+func TestQueue(T *testing.T) {
+	q := New[int, string]()
+	if _, _, ok := q.PopMin(); ok {
+		T.Fatal("pop from empty queue")
+	}
+	for i, p := range []int{3, 1, 2, 1, 3, 2} {
+		q.Push(p, string(rune('a'+i)))
+	}
+	if p, v, ok := q.PeekMax(); !ok || p != 3 || v != "a" {
+		T.Fatalf("peek max: (%d, %q, %v)", p, v, ok)
+	}
+	if p, v, ok := q.PeekMin(); !ok || p != 1 || v != "b" {
+		T.Fatalf("peek min: (%d, %q, %v)", p, v, ok)
+	}
+	var scanned string
+	q.Scan(func(p int, v string) bool {
+		scanned += v
+		return true
+	})
+	if scanned != "bdcfae" {
+		T.Fatalf("scan: %q", scanned)
+	}
+	other := New[int, string]()
+	other.Push(2, "x")
+	other.Push(0, "y")
+	q.Merge(other)
+	if other.Len() != 0 || q.Len() != 8 {
+		T.Fatalf("merge: lens %d and %d", q.Len(), other.Len())
+	}
+	var popped string
+	for _, max := range []bool{false, true, false, true, false, true, false, true} {
+		pop := q.PopMin
+		if max {
+			pop = q.PopMax
+		}
+		_, v, ok := pop()
+		if !ok {
+			T.Fatal("queue is empty too early")
+		}
+		popped += v
+	}
+	if popped != "yabedcfx" {
+		T.Fatalf("popped %q", popped)
+	}
+	if q.Len() != 0 {
+		T.Fatalf("len %d after popping all", q.Len())
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bptreeresp serves a BPTree over the Redis serialization protocol (RESP), so Redis clients
+// can query an ordered index during development and testing.
+//
+// Supported commands: PING, ECHO, QUIT, GET, SET (with NX and XX), DEL, EXISTS, DBSIZE,
+// SCAN (with MATCH and COUNT) and ZRANGEBYLEX (with LIMIT). ZRANGEBYLEX treats the whole tree
+// as a single sorted set of its keys, so its key argument is ignored.
+package bptreeresp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dmitrydikun/bptree"
+)
+
+// Server serves a tree over RESP. Access to the tree is serialized by the server,
+// so the tree must not be used directly while the server is running.
+type Server struct {
+	mu sync.Mutex
+	t  *bptree.BPTree[string, string]
+}
+
+// NewServer returns a Server for a given tree.
+func NewServer(t *bptree.BPTree[string, string]) *Server {
+	return &Server{t: t}
+}
+
+// Serve accepts connections on a listener and serves each of them in a new goroutine.
+// Serve returns when the listener fails, e.g. after it has been closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			_ = s.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn serves a single connection until the client quits or the connection fails, and closes it.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) error {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			writeError(w, "ERR protocol error: "+err.Error())
+			_ = w.Flush()
+			return err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := s.exec(w, args)
+		if err = w.Flush(); err != nil || quit {
+			return err
+		}
+	}
+}
+
+// arity holds minimal and maximal numbers of arguments of supported commands, -1 means unlimited.
+var arity = map[string][2]int{
+	"PING": {0, 1}, "ECHO": {1, 1}, "QUIT": {0, 0}, "GET": {1, 1}, "SET": {2, 4}, "DEL": {1, -1},
+	"EXISTS": {1, -1}, "DBSIZE": {0, 0}, "SCAN": {1, -1}, "ZRANGEBYLEX": {3, 6},
+}
+
+func (s *Server) exec(w *bufio.Writer, args []string) (quit bool) {
+	cmd := strings.ToUpper(args[0])
+	n, ok := arity[cmd]
+	if !ok {
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+	args = args[1:]
+	if len(args) < n[0] || (n[1] >= 0 && len(args) > n[1]) {
+		writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch cmd {
+	case "PING":
+		if len(args) == 0 {
+			writeSimple(w, "PONG")
+		} else {
+			writeBulk(w, &args[0])
+		}
+	case "ECHO":
+		writeBulk(w, &args[0])
+	case "QUIT":
+		writeSimple(w, "OK")
+		return true
+	case "GET":
+		if v, ok := s.t.Find(args[0]); ok {
+			writeBulk(w, &v)
+		} else {
+			writeBulk(w, nil)
+		}
+	case "SET":
+		s.set(w, args)
+	case "DEL":
+		deleted := 0
+		for _, k := range args {
+			if _, ok := s.t.DeleteAll(k); ok {
+				deleted++
+			}
+		}
+		writeInt(w, deleted)
+	case "EXISTS":
+		found := 0
+		for _, k := range args {
+			if _, ok := s.t.Find(k); ok {
+				found++
+			}
+		}
+		writeInt(w, found)
+	case "DBSIZE":
+		writeInt(w, s.t.Size())
+	case "SCAN":
+		s.scan(w, args)
+	case "ZRANGEBYLEX":
+		s.zrangeByLex(w, args[1:])
+	}
+	return false
+}
+
+func (s *Server) set(w *bufio.Writer, args []string) {
+	var nx, xx bool
+	for _, opt := range args[2:] {
+		switch strings.ToUpper(opt) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+	if nx && xx {
+		writeError(w, "ERR syntax error")
+		return
+	}
+	if nx || xx {
+		if _, ok := s.t.Find(args[0]); ok == nx {
+			writeBulk(w, nil)
+			return
+		}
+	}
+	s.t.Insert(args[0], args[1])
+	writeSimple(w, "OK")
+}
+
+// scan implements SCAN with cursor being "0" at the start and the end of a scan, or otherwise "(" followed
+// by the last key examined, so a call resumes with a single descent to that key. Keys present in the tree
+// during the whole scan are returned exactly once. Cursors are not numbers, so clients must pass them as is.
+func (s *Server) scan(w *bufio.Writer, args []string) {
+	var after *string
+	if args[0] != "0" {
+		if !strings.HasPrefix(args[0], "(") {
+			writeError(w, "ERR invalid cursor")
+			return
+		}
+		k := args[0][1:]
+		after = &k
+	}
+	var err error
+	pattern, count := "", 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			writeError(w, "ERR syntax error")
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count <= 0 {
+				writeError(w, "ERR value is not an integer or out of range")
+				return
+			}
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+	var keys []string
+	next := "0"
+	examined := 0
+	last := after
+	it := s.t.Iterator(after, nil)
+	for kv, ok := it.Next(); ok; kv, ok = it.Next() {
+		if last != nil && *last == kv.Key {
+			continue
+		}
+		if examined == count {
+			next = "(" + *last
+			break
+		}
+		k := kv.Key
+		last = &k
+		examined++
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, k); !ok {
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+	fmt.Fprintf(w, "*2\r\n")
+	writeBulk(w, &next)
+	writeArray(w, keys)
+}
+
+func (s *Server) zrangeByLex(w *bufio.Writer, args []string) {
+	from, fromIncl, fromEmpty, err := parseLexBound(args[0], false)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	to, toIncl, toEmpty, err := parseLexBound(args[1], true)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	offset, count := 0, -1
+	if len(args) > 2 {
+		if len(args) != 5 || strings.ToUpper(args[2]) != "LIMIT" {
+			writeError(w, "ERR syntax error")
+			return
+		}
+		offset, err = strconv.Atoi(args[3])
+		if err == nil {
+			count, err = strconv.Atoi(args[4])
+		}
+		if err != nil {
+			writeError(w, "ERR value is not an integer or out of range")
+			return
+		}
+	}
+	keys := []string{}
+	if fromEmpty || toEmpty {
+		writeArray(w, keys)
+		return
+	}
+	var last *string
+	it := s.t.Iterator(from, nil)
+	for kv, ok := it.Next(); ok && count != 0; kv, ok = it.Next() {
+		if last != nil && *last == kv.Key {
+			continue
+		}
+		k := kv.Key
+		last = &k
+		if from != nil && !fromIncl && k == *from {
+			continue
+		}
+		if to != nil && (k > *to || (!toIncl && k == *to)) {
+			break
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		keys = append(keys, k)
+		if count > 0 {
+			count--
+		}
+	}
+	writeArray(w, keys)
+}
+
+// parseLexBound parses a ZRANGEBYLEX min bound, or max bound if upper is true. Unbounded "-" as min and "+" as max
+// are returned as nil, while "+" as min and "-" as max are reported as empty, since no key is in such a range.
+func parseLexBound(s string, upper bool) (b *string, incl, empty bool, err error) {
+	switch {
+	case s == "-" || s == "+":
+		return nil, true, (s == "+") != upper, nil
+	case strings.HasPrefix(s, "["):
+		b := s[1:]
+		return &b, true, false, nil
+	case strings.HasPrefix(s, "("):
+		b := s[1:]
+		return &b, false, false, nil
+	}
+	return nil, false, false, errors.New("ERR min or max not valid string range item")
+}
+
+// Limits of a command sent as a RESP array, the same as default limits of Redis.
+const (
+	maxMultibulkLen = 1024 * 1024 // number of arguments
+	maxBulkLen      = 512 << 20   // length of an argument in bytes
+)
+
+// readCommand reads a command either as a RESP array of bulk strings or as an inline command.
+// A null array is read as an empty command. Memory is allocated as arguments arrive rather than
+// by lengths announced in headers, so a short request can not make the server allocate much.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < -1 || n > maxMultibulkLen {
+		return nil, errors.New("invalid multibulk length")
+	}
+	if n == -1 {
+		return nil, nil
+	}
+	var args []string
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got '%.1s'", line)
+		}
+		l, err := strconv.Atoi(line[1:])
+		if err != nil || l < 0 || l > maxBulkLen {
+			return nil, errors.New("invalid bulk length")
+		}
+		buf.Reset()
+		if _, err = io.CopyN(&buf, r, int64(l)+2); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		b := buf.Bytes()
+		if b[l] != '\r' || b[l+1] != '\n' {
+			return nil, errors.New("bulk string is not terminated by CRLF")
+		}
+		args = append(args, string(b[:l]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeError(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "-%s\r\n", s)
+}
+
+func writeInt(w *bufio.Writer, i int) {
+	fmt.Fprintf(w, ":%d\r\n", i)
+}
+
+// writeBulk writes a bulk string, or a null bulk string if s is nil.
+func writeBulk(w *bufio.Writer, s *string) {
+	if s == nil {
+		fmt.Fprintf(w, "$-1\r\n")
+		return
+	}
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(*s), *s)
+}
+
+func writeArray(w *bufio.Writer, a []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(a))
+	for i := range a {
+		writeBulk(w, &a[i])
+	}
+}
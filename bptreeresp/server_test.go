@@ -0,0 +1,194 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptreeresp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dmitrydikun/bptree"
+)
+
+type client struct {
+	T    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) do(args ...string) any {
+	fmt.Fprintf(c.conn, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.conn, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return c.reply()
+}
+
+func (c *client) reply() any {
+	line, err := readLine(c.r)
+	if err != nil {
+		c.T.Fatal(err)
+	}
+	switch line[0] {
+	case '+':
+		return line[1:]
+	case '-':
+		return fmt.Errorf("%s", line[1:])
+	case ':':
+		n, _ := strconv.Atoi(line[1:])
+		return n
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil
+		}
+		s, _ := readLine(c.r)
+		return s
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		a := make([]any, n)
+		for i := range a {
+			a[i] = c.reply()
+		}
+		return a
+	}
+	c.T.Fatalf("invalid reply: %q", line)
+	return nil
+}
+
+func (c *client) expect(reply any, args ...string) {
+	if r := c.do(args...); !reflect.DeepEqual(r, reply) {
+		c.T.Fatalf("%s: reply %#v, needed %#v", strings.Join(args, " "), r, reply)
+	}
+}
+
+func strs(s ...string) []any {
+	a := make([]any, len(s))
+	for i := range s {
+		a[i] = s[i]
+	}
+	return a
+}
+
+func TestServer(T *testing.T) {
+	s := NewServer(bptree.NewBPTree[string, string](4))
+	conn, sconn := net.Pipe()
+	done := make(chan error)
+	go func() {
+		done <- s.ServeConn(sconn)
+	}()
+	c := &client{T: T, conn: conn, r: bufio.NewReader(conn)}
+	c.expect("PONG", "PING")
+	c.expect("OK", "SET", "b", "vb")
+	c.expect(nil, "SET", "b", "x", "NX")
+	c.expect(nil, "SET", "a", "x", "XX")
+	for _, k := range []string{"a", "c", "d", "e", "f"} {
+		c.expect("OK", "SET", k, "v"+k)
+	}
+	c.expect("vb", "GET", "b")
+	c.expect(nil, "GET", "x")
+	c.expect(2, "EXISTS", "a", "x", "b")
+	c.expect(6, "DBSIZE")
+	c.expect(strs("b", "c", "d"), "ZRANGEBYLEX", "idx", "[b", "(e")
+	c.expect(strs("c", "d"), "ZRANGEBYLEX", "idx", "(b", "[d")
+	c.expect(strs("b", "c"), "ZRANGEBYLEX", "idx", "-", "+", "LIMIT", "1", "2")
+	c.expect(strs(), "ZRANGEBYLEX", "idx", "+", "+")
+	c.expect(strs(), "ZRANGEBYLEX", "idx", "-", "-")
+	c.expect(strs(), "ZRANGEBYLEX", "idx", "+", "-")
+	c.expect([]any{"(d", strs("a", "b", "c", "d")}, "SCAN", "0", "COUNT", "4")
+	c.expect(2, "DEL", "a", "c")
+	c.expect([]any{"0", strs("e", "f")}, "SCAN", "(d", "COUNT", "4")
+	c.expect([]any{"(b", strs("b")}, "SCAN", "0", "COUNT", "1")
+	c.expect("OK", "SET", "a", "va")
+	c.expect("OK", "SET", "c", "vc")
+	if _, ok := c.do("SCAN", "4").(error); !ok {
+		T.Fatal("SCAN with a numeric cursor must fail")
+	}
+	c.expect([]any{"0", strs("f")}, "SCAN", "0", "MATCH", "f*")
+	c.expect(1, "DEL", "a", "x")
+	c.expect(nil, "GET", "a")
+	if _, ok := c.do("GET").(error); !ok {
+		T.Fatal("GET without key must fail")
+	}
+	if _, ok := c.do("FOO").(error); !ok {
+		T.Fatal("unknown command must fail")
+	}
+	fmt.Fprintf(conn, "PING inline\r\n")
+	if r := c.reply(); r != "inline" {
+		T.Fatalf("inline ping: reply %#v", r)
+	}
+	c.expect("OK", "QUIT")
+	if err := <-done; err != nil {
+		T.Fatal(err)
+	}
+}
+
+func TestServerInvalidLengths(T *testing.T) {
+	for _, c := range []struct {
+		name, input, reply string
+	}{
+		{"negative array", "*-2\r\n", "ERR protocol error: invalid multibulk length"},
+		{"huge array", "*" + strconv.Itoa(maxMultibulkLen+1) + "\r\n", "ERR protocol error: invalid multibulk length"},
+		{"huge bulk", "*1\r\n$" + strconv.Itoa(maxBulkLen+1) + "\r\n", "ERR protocol error: invalid bulk length"},
+		{"overflowing bulk", "*1\r\n$9223372036854775807\r\n", "ERR protocol error: invalid bulk length"},
+		{"unterminated bulk", "*1\r\n$1\r\nabc\r\n", "ERR protocol error: bulk string is not terminated by CRLF"},
+	} {
+		s := NewServer(bptree.NewBPTree[string, string](4))
+		conn, sconn := net.Pipe()
+		done := make(chan error)
+		go func() {
+			done <- s.ServeConn(sconn)
+		}()
+		cl := &client{T: T, conn: conn, r: bufio.NewReader(conn)}
+		fmt.Fprint(conn, c.input)
+		if r, ok := cl.reply().(error); !ok || r.Error() != c.reply {
+			T.Fatalf("%s: reply %#v, needed error %q", c.name, r, c.reply)
+		}
+		if err := <-done; err == nil {
+			T.Fatalf("%s: connection is not closed with an error", c.name)
+		}
+	}
+}
+
+func TestReadCommandAllocation(T *testing.T) {
+	input := "*" + strconv.Itoa(maxMultibulkLen) + "\r\n$" + strconv.Itoa(maxBulkLen) + "\r\nabc"
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := readCommand(bufio.NewReader(strings.NewReader(input))); err != io.ErrUnexpectedEOF {
+		T.Fatalf("error %v, needed %v", err, io.ErrUnexpectedEOF)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		T.Fatalf("%d bytes allocated for a %d bytes request", allocated, len(input))
+	}
+}
+
+func TestServerNullArray(T *testing.T) {
+	s := NewServer(bptree.NewBPTree[string, string](4))
+	conn, sconn := net.Pipe()
+	go func() {
+		_ = s.ServeConn(sconn)
+	}()
+	c := &client{T: T, conn: conn, r: bufio.NewReader(conn)}
+	fmt.Fprint(conn, "*-1\r\n")
+	c.expect("PONG", "PING")
+	c.expect("OK", "QUIT")
+}
@@ -16,9 +16,11 @@ package bptree
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"runtime"
 	"sort"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -37,7 +39,7 @@ const (
 
 func fail[K Key, V any](T *testing.T, t *BPTree[K, V], args ...any) {
 	fmt.Println()
-	printTree(t)
+	fmt.Print(t)
 	T.Fatal(args...)
 }
 
@@ -45,162 +47,6 @@ func failf[K Key, V any](T *testing.T, t *BPTree[K, V], format string, args ...a
 	fail(T, t, fmt.Errorf(format, args...))
 }
 
-func printTree[K Key, V any](t *BPTree[K, V]) {
-	var printNode func(n *node[K, V], label string)
-	printNode = func(n *node[K, V], label string) {
-		content := ""
-		for i, k := range n.keys {
-			if i != 0 {
-				content += " "
-			}
-			if n.isLeaf() {
-				if v, ok := n.values[i].(collision[K]); ok {
-					content += fmt.Sprintf("(%v: ", k)
-					for i, v := range v {
-						if i != 0 {
-							content += ", "
-						}
-						content += fmt.Sprint(v)
-					}
-					content += ")"
-				} else {
-					content += fmt.Sprintf("(%v: %v)", k, n.values[i])
-				}
-			} else {
-				content += fmt.Sprintf("[%v]", k)
-			}
-		}
-		fmt.Printf("%.15s: %s\n", label, content)
-		for i, c := range n.children {
-			l := label + "-"
-			if i < len(n.keys) {
-				l += fmt.Sprint(n.keys[i])
-			} else {
-				l += ">"
-			}
-			printNode(c, l)
-		}
-	}
-	printNode(t.root, "root")
-}
-
-func validateTree[K Key, V any](t *BPTree[K, V]) error {
-	maxDepth, numVisited, numOnLevels := -1, 0, 0
-	var visitNode func(n *node[K, V], min, max *K, depth int) error
-	visitNode = func(n *node[K, V], min, max *K, depth int) error {
-		numVisited++
-		if n.isLeaf() {
-			if maxDepth == -1 {
-				maxDepth = depth
-			} else if maxDepth != depth {
-				return fmt.Errorf("maxDepth(%d) != depth(%d)", maxDepth, depth)
-			}
-			if len(n.keys) != len(n.values) {
-				return fmt.Errorf("len(leaf.keys)(%d) != len(leaf.values)(%d)", len(n.keys), len(n.values))
-			}
-			if depth != 0 && len(n.keys) < n.bmin {
-				return fmt.Errorf("len(leaf.keys)(%d) < bmin(%d)", len(n.keys), n.bmin)
-			}
-			if depth != 0 {
-				for _, k := range n.keys {
-					if min != nil && k < *min {
-						return fmt.Errorf("leaf.key(%v) < min(%v)", k, *min)
-					} else if max != nil && k >= *max {
-						return fmt.Errorf("leaf.key(%v) >= max(%v)", k, *max)
-					}
-				}
-			}
-		} else {
-			if len(n.keys) != len(n.children)-1 {
-				return fmt.Errorf("len(node.keys)(%d) != len(node.children)-1(%d)", len(n.keys), len(n.children)-1)
-			}
-			if depth != 0 && len(n.children) < n.bmin {
-				return fmt.Errorf("len(node.children)(%d) < bmin(%d)", len(n.children), n.bmin)
-			}
-			for i, c := range n.children {
-				if i < len(n.keys) {
-					if min != nil && n.keys[i] < *min {
-						return fmt.Errorf("node.key(%v) < min(%v)", n.keys[i], *min)
-					} else if max != nil && n.keys[i] >= *max {
-						return fmt.Errorf("node.key(%v) >= max(%v)", n.keys[i], *max)
-					}
-				}
-				var cmin, cmax *K
-				if i == 0 {
-					cmin = min
-					if len(n.keys) == 0 {
-						cmax = max
-					} else {
-						cmax = &(n.keys[0])
-					}
-				} else if i == len(n.keys) {
-					cmin, cmax = &(n.keys[i-1]), max
-				} else {
-					cmin, cmax = &(n.keys[i-1]), &(n.keys[i])
-				}
-				if err := visitNode(c, cmin, cmax, depth+1); err != nil {
-					return err
-				}
-			}
-		}
-		return nil
-	}
-	var checkLevelLinks func(lvl int) error
-	checkLevelLinks = func(lvl int) error {
-		var nodes []*node[K, V]
-		var getLevelNodes func(n *node[K, V], depth int) error
-		getLevelNodes = func(n *node[K, V], depth int) error {
-			if depth == lvl {
-				nodes = append(nodes, n)
-				return nil
-			}
-			if n.isLeaf() {
-				return fmt.Errorf("maxDepth(%d) != depth(%d)", maxDepth, depth)
-			}
-			for _, c := range n.children {
-				if err := getLevelNodes(c, depth+1); err != nil {
-					return err
-				}
-			}
-			return nil
-		}
-		if err := getLevelNodes(t.root, 0); err != nil {
-			return err
-		}
-		numOnLevels += len(nodes)
-		if len(nodes) == 0 {
-			return fmt.Errorf("empty level(%d)", lvl)
-		}
-		for i, n := range nodes {
-			if i == 0 && n.left != nil {
-				return fmt.Errorf("first.left != nil on level(%d)", lvl)
-			}
-			if i != 0 && n.left != nodes[i-1] {
-				return fmt.Errorf("node.left != previous on level(%d)", lvl)
-			}
-			if i == len(nodes)-1 && n.right != nil {
-				return fmt.Errorf("last.right != nil on level(%d)", lvl)
-			}
-			if i != len(nodes)-1 && n.right != nodes[i+1] {
-				return fmt.Errorf("node.right != next on level(%d)", lvl)
-			}
-		}
-		return nil
-	}
-	if err := visitNode(t.root, nil, nil, 0); err != nil {
-		return err
-	}
-	for lvl := 0; lvl <= maxDepth; lvl++ {
-		if err := checkLevelLinks(lvl); err != nil {
-			return err
-		}
-	}
-	if numVisited != numOnLevels {
-		return fmt.Errorf("numVisited(%d) != numOnLevels(%d)", numVisited, numOnLevels)
-	}
-	return nil
-}
-
 func isEmpty[K Key, V any](t *BPTree[K, V]) bool {
 	return t.root.isLeaf() && len(t.root.keys) == 0 && len(t.root.values) == 0
 }
@@ -243,7 +89,7 @@ func shuffleKeys(keys []int) {
 }
 
 func validateInsert[K Key](T *testing.T, t *BPTree[K, string], keys []K, i int) {
-	if err := validateTree(t); err != nil {
+	if err := t.Validate(); err != nil {
 		failf(T, t, "tree validation failed: %s", err)
 	}
 	for j := 0; j <= i; j++ {
@@ -282,11 +128,11 @@ func TestInsert(T *testing.T) {
 
 func makeAppendKeysValues(n int) ([]int, []int) {
 	uniq := genKeys(n)
-	values := genKeys(5*n)
+	values := genKeys(5 * n)
 	var keys []int
 	for _, k := range uniq {
 		n := rand.Intn(5) + 1
-		for i := 0; i < n; i ++ {
+		for i := 0; i < n; i++ {
 			keys = append(keys, k)
 		}
 	}
@@ -303,7 +149,7 @@ func makeTreeAppendWithKeysValues(T *testing.T, b int, keys, values []int) ([]in
 		}
 		fmt.Print(k)
 		t.Append(k, values[i])
-		if t.Size() != i + 1 {
+		if t.Size() != i+1 {
 			failf(T, t, "invalid size: %d, must be %d", t.Size(), i+1)
 		}
 		if v, ok := m[k]; !ok {
@@ -323,7 +169,7 @@ func makeTreeAppend(T *testing.T, b, n int) ([]int, []int, *BPTree[int, int], ma
 }
 
 func compareWithMap(T *testing.T, t *BPTree[int, int], m map[int][]int) {
-	if err := validateTree(t); err != nil {
+	if err := t.Validate(); err != nil {
 		failf(T, t, "tree validation failed: %s", err)
 	}
 	size := 0
@@ -349,7 +195,7 @@ func compareWithMap(T *testing.T, t *BPTree[int, int], m map[int][]int) {
 }
 
 func validateAppend[K Key](T *testing.T, t *BPTree[K, int], keys []K, values []int, i int) {
-	if err := validateTree(t); err != nil {
+	if err := t.Validate(); err != nil {
 		failf(T, t, "tree validation failed: %s", err)
 	}
 	duplicates := make(map[K]int)
@@ -378,7 +224,7 @@ func validateDelete[K Key, V any](T *testing.T, t *BPTree[K, V], keys []K, i int
 	if v, ok := t.Find(keys[i]); ok {
 		failf(T, t, "found after delete: %v", v)
 	}
-	if err := validateTree(t); err != nil {
+	if err := t.Validate(); err != nil {
 		failf(T, t, "tree validation failed: %v", err)
 	}
 }
@@ -475,6 +321,114 @@ func TestDeleteAppend(T *testing.T) {
 	}
 }
 
+func checkVacatedSlots[K Key, V any](T *testing.T, t *BPTree[K, V], n *node[K, V]) {
+	var zero K
+	for _, k := range n.keys[len(n.keys):cap(n.keys)] {
+		if k != zero {
+			failf(T, t, "vacated key slot holds %v", k)
+		}
+	}
+	for _, v := range n.values[len(n.values):cap(n.values)] {
+		if v != nil {
+			failf(T, t, "vacated value slot holds %v", v)
+		}
+	}
+	for _, c := range n.children[len(n.children):cap(n.children)] {
+		if c != nil {
+			fail(T, t, "vacated child slot is not nil")
+		}
+	}
+	for _, c := range n.children {
+		checkVacatedSlots(T, t, c)
+	}
+}
+
+func TestVacatedSlots(T *testing.T) {
+	t := NewBPTree[string, int](4)
+	keys := genKeys(numKeys)
+	for i, k := range keys {
+		t.Insert(strconv.Itoa(k), i)
+		checkVacatedSlots(T, t, t.root)
+	}
+	shuffleKeys(keys)
+	for _, k := range keys {
+		t.Delete(strconv.Itoa(k))
+		checkVacatedSlots(T, t, t.root)
+	}
+}
+
+func TestVersion(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	v := t.Version()
+	changed := func(op string) {
+		if t.Version() == v {
+			failf(T, t, "version is not changed by %s", op)
+		}
+		v = t.Version()
+	}
+	t.Insert(1, 1)
+	changed("insert")
+	t.Append(1, 2)
+	changed("append")
+	t.Delete(1)
+	changed("delete")
+	if t.Delete(2); t.Version() != v {
+		fail(T, t, "version is changed by delete of absent key")
+	}
+	t.Find(1)
+	t.Range(nil, nil)
+	if t.Version() != v {
+		fail(T, t, "version is changed by reads")
+	}
+	t.DeleteAll(1)
+	changed("delete all")
+	t.Clear()
+	changed("clear")
+}
+
+func TestFloatKeys(T *testing.T) {
+	t := NewBPTree[float64, int](MinOrder)
+	keys := genKeys(numKeys)
+	for _, k := range keys {
+		t.Insert(float64(k)/2, k)
+	}
+	t.Insert(math.NaN(), -1)
+	t.Insert(math.Inf(-1), -2)
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+	if v, ok := t.Find(math.NaN()); !ok || v != -1 {
+		failf(T, t, "NaN not found: (%d, %v)", v, ok)
+	}
+	entries := t.Entries()
+	if len(entries) != numKeys+2 || !math.IsNaN(entries[0].Key) || !math.IsInf(entries[1].Key, -1) {
+		fail(T, t, "NaN and -Inf must be the first keys")
+	}
+	for i, kv := range entries[2:] {
+		if kv.Key != float64(i)/2 || kv.Value != i {
+			failf(T, t, "entry %d: (%v, %v)", i, kv.Key, kv.Value)
+		}
+	}
+}
+
+func TestAutoOrder(T *testing.T) {
+	if o := AutoOrder[int64](); o != 170 {
+		T.Fatalf("AutoOrder[int64]() = %d, needed 170", o)
+	}
+	if o := AutoOrder[string](); o != 128 {
+		T.Fatalf("AutoOrder[string]() = %d, needed 128", o)
+	}
+	t := NewBPTree[string, int](0)
+	if o := t.order(); o != AutoOrder[string]() {
+		failf(T, t, "tree order %d, needed %d", o, AutoOrder[string]())
+	}
+	for _, k := range genKeys(numKeys) {
+		t.Insert(valueForKey(k), k)
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
 
 func TestFirstLast(T *testing.T) {
 	t := NewBPTree[int, string](bmax)
@@ -547,6 +501,106 @@ func TestFirstLastAppend(T *testing.T) {
 	}
 }
 
+func TestContains(T *testing.T) {
+	keys, extra := genExtraKeys(numKeys, numExtraKeys)
+	_, _, t, m := makeTreeAppendWithKeysValues(T, bmax, keys, keys)
+	t.SetLazyDeletion(true)
+	t.DeleteAll(keys[0])
+	delete(m, keys[0])
+	for _, k := range extra[1:] {
+		_, needed := m[*k]
+		if ok := t.Contains(*k); ok != needed {
+			failf(T, t, "contains %d: %t, needed %t", *k, ok, needed)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { t.Contains(keys[1]) }); allocs != 0 {
+		failf(T, t, "contains made %v allocations", allocs)
+	}
+}
+
+func TestMinMaxKey(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	if _, ok := t.MinKey(); ok {
+		fail(T, t, "min key found when tree is empty")
+	}
+	if _, ok := t.MaxKey(); ok {
+		fail(T, t, "max key found when tree is empty")
+	}
+	t.SetLazyDeletion(true)
+	for _, k := range genKeys(numKeys) {
+		t.Append(k, k)
+		t.Append(k, k)
+	}
+	for i := 0; i < numKeys/2; i++ {
+		min, ok := t.MinKey()
+		if !ok || min != i {
+			failf(T, t, "min key (%d, %t), needed (%d, true)", min, ok, i)
+		}
+		max, ok := t.MaxKey()
+		if !ok || max != numKeys-1-i {
+			failf(T, t, "max key (%d, %t), needed (%d, true)", max, ok, numKeys-1-i)
+		}
+		t.DeleteAll(min)
+		t.DeleteAll(max)
+	}
+	if _, ok := t.MinKey(); ok {
+		fail(T, t, "min key found when all keys are deleted")
+	}
+	if _, ok := t.MaxKey(); ok {
+		fail(T, t, "max key found when all keys are deleted")
+	}
+}
+
+func TestPopFirstLast(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+	entries := t.Entries()
+	for len(entries) > 0 {
+		first, ok := t.PopFirst()
+		if !ok || first.Key != entries[0].Key || first.Value != entries[0].Value {
+			failf(T, t, "popped first (%v, %t), needed %v", first, ok, entries[0])
+		}
+		entries = entries[1:]
+		if len(entries) == 0 {
+			break
+		}
+		last, ok := t.PopLast()
+		if !ok || last.Key != entries[len(entries)-1].Key || last.Value != entries[len(entries)-1].Value {
+			failf(T, t, "popped last (%v, %t), needed %v", last, ok, entries[len(entries)-1])
+		}
+		entries = entries[:len(entries)-1]
+		if t.Size() != len(entries) {
+			failf(T, t, "size %d, needed %d", t.Size(), len(entries))
+		}
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+	if _, ok := t.PopFirst(); ok {
+		fail(T, t, "first popped from empty tree")
+	}
+	if _, ok := t.PopLast(); ok {
+		fail(T, t, "last popped from empty tree")
+	}
+
+	// leaves of only tombstones at both ends are skipped
+	_, _, t, _ = makeTreeAppend(T, MinOrder, numKeys)
+	t.SetLazyDeletion(true)
+	entries = t.Entries()
+	for _, kv := range append(entries[:numKeys/10:numKeys/10], entries[len(entries)-numKeys/10:]...) {
+		t.DeleteAll(kv.Key)
+	}
+	entries = t.Entries()
+	if first, ok := t.PopFirst(); !ok || first != entries[0] {
+		failf(T, t, "popped first (%v, %t) after lazy deletion, needed %v", first, ok, entries[0])
+	}
+	if last, ok := t.PopLast(); !ok || last != entries[len(entries)-1] {
+		failf(T, t, "popped last (%v, %t) after lazy deletion, needed %v", last, ok, entries[len(entries)-1])
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
+
 func TestRange(T *testing.T) {
 	b, n, ne := bmax, numRangeTestKeys, numExtraKeys
 	t := NewBPTree[int, string](b)
@@ -743,6 +797,74 @@ func TestIteratorAppend(T *testing.T) {
 	}
 }
 
+func TestIteratorReset(T *testing.T) {
+	b, n, ne := bmax, numRangeTestKeys, numExtraKeys
+	_, values := makeAppendKeysValues(n)
+	keys, extraKeys := genExtraKeys(n, ne)
+	_, _, t, _ := makeTreeAppendWithKeysValues(T, b, keys, values)
+	iter := t.NewIterator(nil, nil)
+	for _, from := range extraKeys {
+		for _, to := range extraKeys {
+			iter.Reset(from, to)
+			var iterRange []KeyValue[int, int]
+			for kv, ok := iter.Next(); ok; kv, ok = iter.Next() {
+				iterRange = append(iterRange, kv)
+			}
+			compareItems(T, t, "Reset", iterRange, t.Range(from, to))
+		}
+	}
+	from, to := keys[0], keys[len(keys)-1]
+	allocs := testing.AllocsPerRun(100, func() {
+		iter.Reset(&from, &to)
+		for _, ok := iter.Next(); ok; _, ok = iter.Next() {
+		}
+	})
+	if allocs != 0 {
+		T.Fatalf("Reset and Next allocated %v times", allocs)
+	}
+}
+
+func TestIteratorModified(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+	iter := t.NewIterator(nil, nil)
+	var n int
+	for kv, ok := iter.Next(); ok; kv, ok = iter.Next() {
+		if n++; n == 10 {
+			t.DeleteAll(kv.Key)
+		}
+	}
+	if n != 10 || iter.Err() != ErrModified {
+		failf(T, t, "%d pairs iterated, error %v", n, iter.Err())
+	}
+	iter.Reset(nil, nil)
+	if _, ok := iter.Next(); !ok || iter.Err() != nil {
+		failf(T, t, "iterator is not reset, error %v", iter.Err())
+	}
+}
+
+func TestValuesInRange(T *testing.T) {
+	b, n, ne := bmax, numRangeTestKeys, numExtraKeys
+	_, values := makeAppendKeysValues(n)
+	keys, extraKeys := genExtraKeys(n, ne)
+	_, _, t, _ := makeTreeAppendWithKeysValues(T, b, keys, values)
+	buf := make([]int, 0, n)
+	for _, from := range extraKeys {
+		for _, to := range extraKeys {
+			treeRange := t.Range(from, to)
+			vals := t.ValuesInRange(from, to)
+			buf = t.AppendValuesInRange(buf[:0], from, to)
+			if len(vals) != len(treeRange) || len(buf) != len(treeRange) {
+				T.Fatalf("invalid len(values): %d, %d, needed %d", len(vals), len(buf), len(treeRange))
+			}
+			for i, kv := range treeRange {
+				if vals[i] != kv.Value || buf[i] != kv.Value {
+					T.Fatalf("values[%d] (%d, %d) != %d", i, vals[i], buf[i], kv.Value)
+				}
+			}
+		}
+	}
+}
+
 func printMemStats(msg string, old *runtime.MemStats) *runtime.MemStats {
 	runtime.GC()
 	var ms runtime.MemStats
@@ -801,7 +923,7 @@ func _TestDebug(T *testing.T) {
 		//}
 		//fmt.Print(k)
 		t.Append(k, values[i])
-		printTree(t)
+		fmt.Print(t)
 		validateAppend(T, t, keys, values, i)
 	}
 	fmt.Println()
@@ -816,6 +938,19 @@ func BenchmarkBPTreeInsert(b *testing.B) {
 	}
 }
 
+func BenchmarkBPTreeFindString(b *testing.B) {
+	t := NewBPTree[string, int](bmax)
+	keys := make([]string, numKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("https://example.com/some/long/common/prefix/%08d", i)
+		t.Insert(keys[i], i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		t.Find(keys[i%numKeys])
+	}
+}
+
 func BenchmarkMapInsert(b *testing.B) {
 	m := make(map[int]any)
 	keys := genKeys(benchNumKeys)
@@ -872,4 +1007,3 @@ func BenchmarkAllocatedMapFind(b *testing.B) {
 		_, _ = m[k]
 	}
 }
-
@@ -0,0 +1,151 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsorted is returned by NewBPTreeFromSorted if pairs are not sorted by key.
+var ErrUnsorted = errors.New("pairs are not sorted by key")
+
+// NewBPTreeFromSorted returns a new tree of a given order (see NewBPTree) with key-value pairs sorted by key.
+// Values of equal keys are stored in the order they are given, like by Append. Unlike inserting pairs one
+// by one, tree is built bottom-up in linear time, with all nodes filled up to order. Returns ErrUnsorted
+// if pairs are not sorted, and an error if a value is not of type V.
+func NewBPTreeFromSorted[K Key, V any](order int, kvs []KeyValue[K, V]) (*BPTree[K, V], error) {
+	keys := make([]K, len(kvs))
+	values := make([]V, len(kvs))
+	for i, kv := range kvs {
+		if i > 0 && cmp.Less(kv.Key, keys[i-1]) {
+			return nil, ErrUnsorted
+		}
+		v, ok := kv.Value.(V)
+		if !ok && kv.Value != nil {
+			return nil, fmt.Errorf("value %T of pair %d is not %T", kv.Value, i, v)
+		}
+		keys[i], values[i] = kv.Key, v
+	}
+	ukeys, uvalues := groupSorted(keys, values)
+	t := buildTree[K, V](order, ukeys, uvalues)
+	t.counters.Inserts = uint64(t.size)
+	return t, nil
+}
+
+// groupSorted groups values of equal keys sorted in ascending order into collisions, returning unique keys
+// and their values (either V or collision[V]) for buildTree.
+func groupSorted[K Key, V any](keys []K, values []V) ([]K, []any) {
+	var ukeys []K
+	var uvalues []any
+	for i, k := range keys {
+		if len(ukeys) == 0 || cmp.Compare(ukeys[len(ukeys)-1], k) != 0 {
+			ukeys = append(ukeys, k)
+			uvalues = append(uvalues, values[i])
+			continue
+		}
+		switch v := uvalues[len(uvalues)-1].(type) {
+		case collision[V]:
+			uvalues[len(uvalues)-1] = append(v, values[i])
+		default:
+			uvalues[len(uvalues)-1] = collision[V]{v.(V), values[i]}
+		}
+	}
+	return ukeys, uvalues
+}
+
+// lastOfEqual keeps only the last value of equal keys sorted in ascending order, as inserting them one by one
+// would. Keys and values are compacted in place.
+func lastOfEqual[K Key, V any](keys []K, values []V) ([]K, []V) {
+	j := 0
+	for i, k := range keys {
+		if j > 0 && cmp.Compare(keys[j-1], k) == 0 {
+			values[j-1] = values[i]
+			continue
+		}
+		keys[j], values[j] = k, values[i]
+		j++
+	}
+	return keys[:j], values[:j]
+}
+
+// buildTree returns a tree of a given order built bottom-up from sorted unique keys and their values
+// (either V or collision[V]). All nodes are filled up to order, except the last two nodes of each level,
+// which share their pairs or children so that both have at least the minimal allowed number of them.
+func buildTree[K Key, V any](order int, keys []K, values []any) *BPTree[K, V] {
+	return fillTree(NewBPTree[K, V](order), keys, values)
+}
+
+// fillTree builds the content of empty tree t like buildTree, without values if tree has keys only.
+func fillTree[K Key, V any](t *BPTree[K, V], keys []K, values []any) *BPTree[K, V] {
+	if len(keys) == 0 {
+		return t
+	}
+	order := cap(t.root.keys)
+	bmin := t.root.bmin
+	var level []*node[K, V]
+	var mins []K
+	for _, b := range chunkBounds(len(keys), order, bmin) {
+		var n *node[K, V]
+		if t.keysOnly {
+			n = newKeysLeafNode[K, V](order)
+		} else {
+			n = newLeafNode[K, V](order)
+			n.values = append(n.values, values[b[0]:b[1]]...)
+		}
+		n.keys = append(n.keys, keys[b[0]:b[1]]...)
+		n.count = leafCount(n)
+		t.size += n.count
+		level = append(level, n)
+		mins = append(mins, n.keys[0])
+	}
+	for {
+		for i := 1; i < len(level); i++ {
+			level[i-1].right = level[i]
+			level[i].left = level[i-1]
+		}
+		if len(level) == 1 {
+			break
+		}
+		var parents []*node[K, V]
+		var pmins []K
+		for _, b := range chunkBounds(len(level), order, bmin) {
+			n := newInternalNode[K, V](order)
+			n.children = append(n.children, level[b[0]:b[1]]...)
+			n.keys = append(n.keys, mins[b[0]+1:b[1]]...)
+			n.count = childrenCount(n)
+			parents = append(parents, n)
+			pmins = append(pmins, mins[b[0]])
+		}
+		level, mins = parents, pmins
+	}
+	t.root = level[0]
+	return t
+}
+
+// chunkBounds splits n items into chunks of size items, moving items from the penultimate chunk
+// to the last one if it has less than bmin items.
+func chunkBounds(n, size, bmin int) [][2]int {
+	var bounds [][2]int
+	for i := 0; i < n; i += size {
+		bounds = append(bounds, [2]int{i, min(i+size, n)})
+	}
+	if l := len(bounds); l > 1 && bounds[l-1][1]-bounds[l-1][0] < bmin {
+		bounds[l-2][1] = n - bmin
+		bounds[l-1][0] = n - bmin
+	}
+	return bounds
+}
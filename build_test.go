@@ -0,0 +1,54 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewBPTreeFromSorted(T *testing.T) {
+	for _, n := range []int{0, 1, bmax - 1, bmax, bmax + 1, numKeys} {
+		keys, values := makeAppendKeysValues(n)
+		kvs := make([]KeyValue[int, int], len(keys))
+		for i, k := range keys {
+			kvs[i] = KeyValue[int, int]{Key: k, Value: values[i]}
+		}
+		slices.SortStableFunc(kvs, func(a, b KeyValue[int, int]) int { return a.Key - b.Key })
+		t, err := NewBPTreeFromSorted(bmax, kvs)
+		if err != nil {
+			T.Fatal(err)
+		}
+		_, _, t2, m := makeTreeAppendWithKeysValues(T, bmax, keys, values)
+		compareWithMap(T, t, m)
+		compareItems(T, t, "NewBPTreeFromSorted", t.Entries(), t2.Entries())
+		if t.Counters().Inserts != uint64(len(kvs)) {
+			failf(T, t, "%d inserts counted, needed %d", t.Counters().Inserts, len(kvs))
+		}
+		t.Append(n, n)
+		t.Delete(0)
+		if err := t.Validate(); err != nil {
+			failf(T, t, "tree validation failed: %s", err)
+		}
+	}
+	kvs := []KeyValue[int, int]{{Key: 1, Value: 1}, {Key: 0, Value: 0}}
+	if _, err := NewBPTreeFromSorted(bmax, kvs); err != ErrUnsorted {
+		T.Errorf("unsorted pairs: %v", err)
+	}
+	kvs = []KeyValue[int, int]{{Key: 0, Value: "0"}}
+	if _, err := NewBPTreeFromSorted(bmax, kvs); err == nil {
+		T.Error("value of invalid type accepted")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"slices"
+)
+
+// Clone returns an independent copy of tree, made node by node without reinserting pairs. Values are copied
+// shallowly. Clone has the same settings as tree (lazy deletion, duplicate orders and options, memory limit
+// and cost), but neither slow operation hook nor recording, and its counters start from zero.
+func (t *BPTree[K, V]) Clone() *BPTree[K, V] {
+	return &BPTree[K, V]{
+		root:         cloneNode(t.root, make([]*node[K, V], t.Height())),
+		size:         t.size,
+		pprofCtx:     t.pprofCtx,
+		lazyDeletion: t.lazyDeletion,
+		tombstones:   t.tombstones,
+		deleteOldest: t.deleteOldest,
+		findAllOrder: t.findAllOrder,
+		noDuplicates: t.noDuplicates,
+		noFreeList:   t.noFreeList,
+		memLimit:     t.memLimit,
+		memCostFn:    t.memCostFn,
+		memCost:      t.memCost,
+	}
+}
+
+// cloneNode returns a copy of subtree n, linking every copied node to the previous one on its level.
+// The last copied node of every level below n is tracked in last, starting from the level of n.
+func cloneNode[K Key, V any](n *node[K, V], last []*node[K, V]) *node[K, V] {
+	n2 := &node[K, V]{keys: make([]K, len(n.keys), cap(n.keys)), bmin: n.bmin, count: n.count}
+	copy(n2.keys, n.keys)
+	if n.isInternal() {
+		n2.children = make([]*node[K, V], len(n.children), cap(n.children))
+		for i, c := range n.children {
+			n2.children[i] = cloneNode(c, last[1:])
+		}
+	} else {
+		n2.values = make([]any, len(n.values), cap(n.values))
+		for i, v := range n.values {
+			if c, ok := v.(collision[V]); ok {
+				v = slices.Clone(c)
+			}
+			n2.values[i] = v
+		}
+	}
+	if last[0] != nil {
+		last[0].right = n2
+		n2.left = last[0]
+	}
+	last[0] = n2
+	return n2
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestClone(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	deleted := t.Entries()[0].Key
+	t.DeleteAll(deleted)
+	delete(m, deleted)
+	entries := t.Entries()
+	c := t.Clone()
+	compareWithMap(T, c, m)
+	if c.Size() != t.Size() || c.Height() != t.Height() {
+		failf(T, c, "clone size %d, height %d, needed %d, %d", c.Size(), c.Height(), t.Size(), t.Height())
+	}
+	for k := range m {
+		c.DeleteOne(k, 0)
+		c.Append(k, -1)
+		c.Append(numKeys+k, k)
+	}
+	if err := c.Validate(); err != nil {
+		failf(T, c, "tree validation failed: %s", err)
+	}
+	compareWithMap(T, t, m)
+	compareItems(T, t, "Clone", t.Entries(), entries)
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+
+	"github.com/dmitrydikun/bptree/internal/keybits"
+)
+
+// ValueCodec converts values to bytes and back, e.g. for ExportStream and ImportStream,
+// which take its methods as encodeValue and decodeValue.
+type ValueCodec[V any] interface {
+	Encode(V) ([]byte, error)
+	Decode([]byte) (V, error)
+}
+
+// BytesCodec is a ValueCodec for []byte values, stored as is.
+type BytesCodec struct{}
+
+func (BytesCodec) Encode(v []byte) ([]byte, error) {
+	return v, nil
+}
+
+// Decode returns a copy of b, so b may be reused by the caller.
+func (BytesCodec) Decode(b []byte) ([]byte, error) {
+	return bytes.Clone(b), nil
+}
+
+// StringCodec is a ValueCodec for string values, stored as their bytes.
+type StringCodec struct{}
+
+func (StringCodec) Encode(v string) ([]byte, error) {
+	return []byte(v), nil
+}
+
+func (StringCodec) Decode(b []byte) (string, error) {
+	return string(b), nil
+}
+
+// GobCodec is a ValueCodec for any values encodable by encoding/gob. Every value is encoded
+// as a separate gob stream, with its own type information.
+type GobCodec[V any] struct{}
+
+func (GobCodec[V]) Encode(v V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[V]) Decode(b []byte) (V, error) {
+	var v V
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v, err
+}
+
+// KeyCodec converts keys to bytes and back. Encodings must preserve order: for keys a and b,
+// bytes.Compare of their encodings must be equal to cmp.Compare(a, b).
+type KeyCodec[K Key] interface {
+	Encode(K) ([]byte, error)
+	Decode([]byte) (K, error)
+}
+
+// OrderedKeyCodec is a KeyCodec for all Key types. Integers are encoded big-endian in their size with
+// the sign bit of signed ones flipped, floats as IEEE 754 bits transformed to compare as unsigned
+// integers (NaN first, like in cmp.Compare), and strings as their bytes.
+type OrderedKeyCodec[K Key] struct{}
+
+func (OrderedKeyCodec[K]) Encode(k K) ([]byte, error) {
+	v := reflect.ValueOf(k)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size := v.Type().Size()
+		return appendBigEndian(nil, keybits.Int(uint64(v.Int()), int(size)), size), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendBigEndian(nil, v.Uint(), v.Type().Size()), nil
+	case reflect.Float32:
+		return appendBigEndian(nil, uint64(keybits.Float32(float32(v.Float()))), 4), nil
+	case reflect.Float64:
+		return appendBigEndian(nil, keybits.Float64(v.Float()), 8), nil
+	case reflect.String:
+		return []byte(v.String()), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", k)
+}
+
+func (OrderedKeyCodec[K]) Decode(b []byte) (K, error) {
+	var k K
+	v := reflect.ValueOf(&k).Elem()
+	size := int(v.Type().Size())
+	if v.Kind() != reflect.String && len(b) != size {
+		return k, fmt.Errorf("invalid length %d of %T key", len(b), k)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		u := keybits.Int(readBigEndian(b), size)
+		v.SetInt(int64(u<<(64-size*8)) >> (64 - size*8))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(readBigEndian(b))
+	case reflect.Float32:
+		v.SetFloat(float64(keybits.FromFloat32(uint32(readBigEndian(b)))))
+	case reflect.Float64:
+		v.SetFloat(keybits.FromFloat64(readBigEndian(b)))
+	case reflect.String:
+		v.SetString(string(b))
+	default:
+		return k, fmt.Errorf("unsupported key type %T", k)
+	}
+	return k, nil
+}
+
+func appendBigEndian(b []byte, u uint64, size uintptr) []byte {
+	for i := int(size) - 1; i >= 0; i-- {
+		b = append(b, byte(u>>(i*8)))
+	}
+	return b
+}
+
+func readBigEndian(b []byte) uint64 {
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"cmp"
+	"math"
+	"testing"
+
+	"github.com/dmitrydikun/bptree/bptreekeycodec"
+)
+
+func testValueCodec[V any](T *testing.T, c ValueCodec[V], vals []V, equal func(a, b V) bool) {
+	for _, v := range vals {
+		b, err := c.Encode(v)
+		if err != nil {
+			T.Fatalf("%T: encode %v: %v", c, v, err)
+		}
+		d, err := c.Decode(b)
+		if err != nil || !equal(d, v) {
+			T.Fatalf("%T: decode %v: (%v, %v)", c, v, d, err)
+		}
+	}
+}
+
+type codecPoint struct {
+	X, Y int
+	Name string
+}
+
+func TestValueCodecs(T *testing.T) {
+	testValueCodec[[]byte](T, BytesCodec{}, [][]byte{nil, {}, {0, 1, 2}}, func(a, b []byte) bool { return bytes.Equal(a, b) })
+	testValueCodec[string](T, StringCodec{}, []string{"", "a", "\x00\xff"}, func(a, b string) bool { return a == b })
+	testValueCodec[codecPoint](T, GobCodec[codecPoint]{}, []codecPoint{{}, {1, -2, "p"}}, func(a, b codecPoint) bool { return a == b })
+	if _, err := (GobCodec[codecPoint]{}).Decode([]byte("garbage")); err == nil {
+		T.Fatal("gob codec decoded garbage")
+	}
+
+	t := NewBPTree[int, codecPoint](bmax)
+	for _, k := range genKeys(numRangeTestKeys) {
+		t.Append(k%10, codecPoint{k, -k, valueForKey(k)})
+	}
+	var c GobCodec[codecPoint]
+	var buf bytes.Buffer
+	if err := t.ExportStream(&buf, encodeIntKey, c.Encode); err != nil {
+		T.Fatal(err)
+	}
+	t2 := NewBPTree[int, codecPoint](bmax)
+	if err := t2.ImportStream(&buf, decodeIntKey, c.Decode); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t2, "ImportStream", t2.Entries(), t.Entries())
+}
+
+func testKeyCodec[K Key](T *testing.T, keys []K) {
+	var c OrderedKeyCodec[K]
+	for _, a := range keys {
+		ea, err := c.Encode(a)
+		if err != nil {
+			T.Fatalf("%T: encode %v: %v", a, a, err)
+		}
+		if d, err := c.Decode(ea); err != nil || cmp.Compare(d, a) != 0 {
+			T.Fatalf("%T: decode %v: (%v, %v)", a, a, d, err)
+		}
+		for _, b := range keys {
+			eb, _ := c.Encode(b)
+			if bytes.Compare(ea, eb) != cmp.Compare(a, b) {
+				T.Fatalf("%T: encodings of %v and %v compare as %d", a, a, b, bytes.Compare(ea, eb))
+			}
+		}
+	}
+}
+
+type codecName string
+
+func TestOrderedKeyCodec(T *testing.T) {
+	testKeyCodec(T, []int{math.MinInt, -1000, -1, 0, 1, 255, 256, math.MaxInt})
+	testKeyCodec(T, []int8{math.MinInt8, -1, 0, 1, math.MaxInt8})
+	testKeyCodec(T, []int32{math.MinInt32, -70000, 0, 70000, math.MaxInt32})
+	testKeyCodec(T, []uint16{0, 1, 255, 256, math.MaxUint16})
+	testKeyCodec(T, []uint64{0, 1, 1 << 40, math.MaxUint64})
+	testKeyCodec(T, []float64{math.NaN(), math.Inf(-1), -math.MaxFloat64, -1.5, -math.SmallestNonzeroFloat64, math.Copysign(0, -1), 0, math.SmallestNonzeroFloat64, 1, 1.5, math.MaxFloat64, math.Inf(1)})
+	testKeyCodec(T, []float32{float32(math.NaN()), float32(math.Inf(-1)), -2.5, 0, 2.5, math.MaxFloat32, float32(math.Inf(1))})
+	testKeyCodec(T, []string{"", "\x00", "a", "a\x00", "ab", "b", "\xff"})
+	testKeyCodec(T, []codecName{"", "x", "y"})
+	if _, err := (OrderedKeyCodec[int32]{}).Decode([]byte{1, 2}); err == nil {
+		T.Fatal("decoded key of invalid length")
+	}
+}
+
+func TestOrderedKeyCodecKeyCodecPackage(T *testing.T) {
+	for _, v := range []int64{math.MinInt64, -1, 0, 1, math.MaxInt64} {
+		if e, _ := (OrderedKeyCodec[int64]{}).Encode(v); !bytes.Equal(bptreekeycodec.AppendInt(nil, v), e) {
+			T.Fatalf("int %d: %x, OrderedKeyCodec %x", v, bptreekeycodec.AppendInt(nil, v), e)
+		}
+	}
+	for _, v := range []float64{math.NaN(), math.Inf(-1), -1, math.Copysign(0, -1), 0, 1, math.Inf(1)} {
+		if e, _ := (OrderedKeyCodec[float64]{}).Encode(v); !bytes.Equal(bptreekeycodec.AppendFloat(nil, v), e) {
+			T.Fatalf("float %v: %x, OrderedKeyCodec %x", v, bptreekeycodec.AppendFloat(nil, v), e)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dmitrydikun/bptree/bptreekeycodec"
+)
+
+// CompositeKey is a key made of several values, e.g. (tenant, timestamp, sequence number), encoded with
+// bptreekeycodec. Since encodings preserve order, composite keys are ordered by their first value, then by
+// the second one, and so on, so they can be used as keys of a tree, and a range of keys sharing leading
+// values can be scanned with PrefixRange.
+type CompositeKey string
+
+// NewCompositeKey returns a composite key of given values, which may be integers, floats, strings,
+// byte slices and times (see bptreekeycodec.AppendTuple).
+func NewCompositeKey(values ...any) (CompositeKey, error) {
+	b, err := bptreekeycodec.AppendTuple(nil, values...)
+	if err != nil {
+		return "", err
+	}
+	return CompositeKey(b), nil
+}
+
+// Scan decodes values of a composite key to dst, which must be pointers to types of the values in order:
+// *int, *int8, *int16, *int32 and *int64 for signed integers, *uint, *uint8, *uint16, *uint32, *uint64
+// and *uintptr for unsigned ones, *float32 and *float64 for floats, *string, *[]byte and *time.Time.
+// Fewer pointers than values may be given to decode only leading ones.
+func (k CompositeKey) Scan(dst ...any) error {
+	b := []byte(k)
+	for i, d := range dst {
+		var err error
+		switch d := d.(type) {
+		case *int:
+			var v int64
+			v, b, err = bptreekeycodec.DecodeInt(b)
+			*d = int(v)
+		case *int8:
+			var v int64
+			v, b, err = bptreekeycodec.DecodeInt(b)
+			*d = int8(v)
+		case *int16:
+			var v int64
+			v, b, err = bptreekeycodec.DecodeInt(b)
+			*d = int16(v)
+		case *int32:
+			var v int64
+			v, b, err = bptreekeycodec.DecodeInt(b)
+			*d = int32(v)
+		case *int64:
+			*d, b, err = bptreekeycodec.DecodeInt(b)
+		case *uint:
+			var v uint64
+			v, b, err = bptreekeycodec.DecodeUint(b)
+			*d = uint(v)
+		case *uint8:
+			var v uint64
+			v, b, err = bptreekeycodec.DecodeUint(b)
+			*d = uint8(v)
+		case *uint16:
+			var v uint64
+			v, b, err = bptreekeycodec.DecodeUint(b)
+			*d = uint16(v)
+		case *uint32:
+			var v uint64
+			v, b, err = bptreekeycodec.DecodeUint(b)
+			*d = uint32(v)
+		case *uint64:
+			*d, b, err = bptreekeycodec.DecodeUint(b)
+		case *uintptr:
+			var v uint64
+			v, b, err = bptreekeycodec.DecodeUint(b)
+			*d = uintptr(v)
+		case *float32:
+			var v float64
+			v, b, err = bptreekeycodec.DecodeFloat(b)
+			*d = float32(v)
+		case *float64:
+			*d, b, err = bptreekeycodec.DecodeFloat(b)
+		case *string:
+			*d, b, err = bptreekeycodec.DecodeString(b)
+		case *[]byte:
+			*d, b, err = bptreekeycodec.DecodeBytes(b)
+		case *time.Time:
+			*d, b, err = bptreekeycodec.DecodeTime(b)
+		default:
+			return fmt.Errorf("unsupported composite key destination type %T", d)
+		}
+		if err != nil {
+			return fmt.Errorf("composite key value %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// PrefixRange returns the interval [*from; *to) of composite keys starting with a given prefix made of
+// leading values, to be passed to Range, Iterator and other methods taking intervals. To is nil if
+// there is no upper bound, and both are nil for an empty prefix.
+func PrefixRange(prefix CompositeKey) (from, to *CompositeKey) {
+	if prefix == "" {
+		return nil, nil
+	}
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] != 0xFF {
+			b[i]++
+			end := CompositeKey(b[:i+1])
+			return &prefix, &end
+		}
+	}
+	return &prefix, nil
+}
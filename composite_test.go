@@ -0,0 +1,81 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompositeKey(T *testing.T) {
+	t := NewBPTree[CompositeKey, int](MinOrder)
+	base := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	tenants := []string{"", "a", "a\x00", "b"}
+	for i := 0; i < 40; i++ {
+		k, err := NewCompositeKey(tenants[i%len(tenants)], base.Add(time.Duration(i%5)*time.Minute), uint32(i))
+		if err != nil {
+			T.Fatal(err)
+		}
+		t.Insert(k, i)
+	}
+	var prev struct {
+		tenant string
+		ts     time.Time
+		seq    uint32
+	}
+	for i, kv := range t.Entries() {
+		var tenant string
+		var ts time.Time
+		var seq uint32
+		if err := kv.Key.Scan(&tenant, &ts, &seq); err != nil {
+			T.Fatal(err)
+		}
+		if int(seq) != kv.Value {
+			failf(T, t, "seq %d, needed %d", seq, kv.Value)
+		}
+		if i > 0 && (tenant < prev.tenant || tenant == prev.tenant && (ts.Before(prev.ts) ||
+			ts.Equal(prev.ts) && seq <= prev.seq)) {
+			failf(T, t, "(%q, %v, %d) is ordered after (%q, %v, %d)", tenant, ts, seq, prev.tenant, prev.ts, prev.seq)
+		}
+		prev.tenant, prev.ts, prev.seq = tenant, ts, seq
+	}
+	prefix, _ := NewCompositeKey("a", base.Add(time.Minute))
+	var seqs []int
+	for _, kv := range t.Range(PrefixRange(prefix)) {
+		seqs = append(seqs, kv.Value.(int))
+	}
+	// tenant "a" is i%4 == 1, and minute 1 is i%5 == 1
+	if len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 21 {
+		failf(T, t, "prefix range: %v, needed [1 21]", seqs)
+	}
+	if from, to := PrefixRange(""); from != nil || to != nil {
+		fail(T, t, "empty prefix range is bounded")
+	}
+	if _, to := PrefixRange("\xFF\xFF"); to != nil {
+		fail(T, t, "prefix range of 0xFF bytes has upper bound")
+	}
+	var tenant string
+	var ts time.Time
+	var seq uint32
+	if err := prefix.Scan(&tenant, &ts, &seq); err == nil {
+		fail(T, t, "missing value scanned")
+	}
+	if err := prefix.Scan(&tenant, &ts); err != nil || tenant != "a" || !ts.Equal(base.Add(time.Minute)) {
+		failf(T, t, "prefix scanned as (%q, %v), %v", tenant, ts, err)
+	}
+	if _, err := NewCompositeKey(struct{}{}); err == nil {
+		fail(T, t, "unsupported value type accepted")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"slices"
+)
+
+// CoversAll checks presence of many keys at once and returns those of them which are not found in tree,
+// in ascending order, or nil if all are present. Keys are probed in sorted order, so that consecutive
+// keys from the same or the next leaf do not descend from the root again.
+func (t *BPTree[K, V]) CoversAll(keys []K) (missing []K) {
+	sorted := slices.Clone(keys)
+	slices.Sort(sorted)
+	var n *node[K, V]
+	for _, key := range sorted {
+		if n == nil || !t.inLeaf(n, key) {
+			if n != nil && n.right != nil && t.inLeaf(n.right, key) {
+				n = n.right
+			} else {
+				n = t.seekLeaf(key)
+			}
+		}
+		if i, found := n.search(key); !found {
+			missing = append(missing, key)
+		} else if _, ok := n.values[i].(tombstone); ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// inLeaf reports whether a given key belongs to leaf n, that is it is not greater than the last key
+// of leaf, and not less than the first one unless it is the leftmost leaf.
+func (t *BPTree[K, V]) inLeaf(n *node[K, V], key K) bool {
+	if len(n.keys) == 0 || cmp.Less(n.keys[len(n.keys)-1], key) {
+		return false
+	}
+	return n.left == nil || !cmp.Less(key, n.keys[0])
+}
+
+// AnyInRange reports whether tree has any key from interval [*from; *to). Nil given as a parameter will
+// be interpreted as begin or end whole tree key diapason.
+func (t *BPTree[K, V]) AnyInRange(from *K, to *K) bool {
+	i := RangeIterator[K, V]{t: t}
+	i.Reset(from, to)
+	_, ok := i.Next()
+	return ok
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCoversAll(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	deleted := t.Entries()[numKeys/2].Key
+	t.DeleteAll(deleted)
+	delete(m, deleted)
+	var probe, needed []int
+	for k := -numExtraKeys; k < numKeys+numExtraKeys; k++ {
+		probe = append(probe, k)
+		if _, ok := m[k]; !ok {
+			needed = append(needed, k)
+		}
+	}
+	shuffleKeys(probe)
+	if missing := t.CoversAll(probe); !slices.Equal(missing, needed) {
+		failf(T, t, "missing %v, needed %v", missing, needed)
+	}
+	var present []int
+	for k := range m {
+		present = append(present, k, k)
+	}
+	if missing := t.CoversAll(present); missing != nil {
+		failf(T, t, "missing %v, needed none", missing)
+	}
+	if missing := t.CoversAll(nil); missing != nil {
+		failf(T, t, "missing %v for no keys", missing)
+	}
+}
+
+func TestAnyInRange(T *testing.T) {
+	keys, extra := genExtraKeys(numRangeTestKeys, numExtraKeys)
+	t := NewBPTree[int, int](bmax)
+	for _, k := range keys {
+		t.Insert(k, k)
+	}
+	for _, from := range extra {
+		for _, to := range extra {
+			needed := len(t.Range(from, to)) > 0
+			if got := t.AnyInRange(from, to); got != needed {
+				failf(T, t, "any in range [%v; %v) is %t, needed %t", from, to, got, needed)
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// Cursor is a bidirectional cursor over key-value pairs of a tree, with semantics similar to bbolt's Cursor.
+// Multiple values of the same key are visited one by one in the order they were appended.
+// If the tree is modified, Next and Prev stop and Err returns ErrModified until the cursor is positioned
+// again with First, Last or Seek.
+type Cursor[K Key, V any] struct {
+	t       *BPTree[K, V]
+	n       *node[K, V]
+	i       int
+	ci      int
+	version uint64
+	err     error
+}
+
+// Cursor returns a new Cursor for the tree. Cursor is not positioned until First, Last or Seek is called.
+func (t *BPTree[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{t: t}
+}
+
+// First moves the cursor to the first pair in the tree and returns it.
+// Returns (zero, zero, false) if tree is empty.
+func (c *Cursor[K, V]) First() (K, V, bool) {
+	n := c.t.root
+	for n.isInternal() {
+		n = n.children[0]
+	}
+	c.reset(n, 0)
+	c.skipForward()
+	return c.current()
+}
+
+// Last moves the cursor to the last pair in the tree and returns it.
+// Returns (zero, zero, false) if tree is empty.
+func (c *Cursor[K, V]) Last() (K, V, bool) {
+	n := c.t.root
+	for n.isInternal() {
+		n = n.children[len(n.children)-1]
+	}
+	c.reset(n, len(n.keys))
+	c.backward()
+	return c.current()
+}
+
+// Seek moves the cursor to the first pair with key greater or equal to a given key and returns it.
+// Returns (zero, zero, false) if there is no such key.
+func (c *Cursor[K, V]) Seek(key K) (K, V, bool) {
+	n := c.t.seekLeaf(key)
+	i, _ := n.search(key)
+	c.reset(n, i)
+	c.skipForward()
+	return c.current()
+}
+
+// Next moves the cursor to the next pair and returns it.
+// Returns (zero, zero, false) if the cursor is at the end of the tree.
+func (c *Cursor[K, V]) Next() (K, V, bool) {
+	if c.n == nil || c.modified() {
+		return c.current()
+	}
+	if col, ok := c.n.values[c.i].(collision[V]); ok && c.ci < len(col)-1 {
+		c.ci++
+		return c.current()
+	}
+	c.i++
+	c.ci = 0
+	c.skipForward()
+	return c.current()
+}
+
+// Prev moves the cursor to the previous pair and returns it.
+// Returns (zero, zero, false) if the cursor is at the beginning of the tree.
+func (c *Cursor[K, V]) Prev() (K, V, bool) {
+	if c.n == nil || c.modified() {
+		return c.current()
+	}
+	if c.ci > 0 {
+		c.ci--
+		return c.current()
+	}
+	c.backward()
+	return c.current()
+}
+
+// Err returns ErrModified if Next or Prev has stopped because the tree has been modified, or nil.
+func (c *Cursor[K, V]) Err() error {
+	return c.err
+}
+
+// reset positions the cursor at a given pair of a leaf, as of the current version of the tree.
+func (c *Cursor[K, V]) reset(n *node[K, V], i int) {
+	c.n, c.i, c.ci = n, i, 0
+	c.version, c.err = c.t.version, nil
+}
+
+// modified checks whether the tree has been modified since the cursor was positioned, and stops it if so.
+func (c *Cursor[K, V]) modified() bool {
+	if c.version == c.t.version {
+		return false
+	}
+	c.n, c.err = nil, ErrModified
+	return true
+}
+
+// skipForward moves the cursor from a position past the end of a leaf to the next leaf,
+// and from tombstones to the next key.
+func (c *Cursor[K, V]) skipForward() {
+	for c.n != nil {
+		if c.i == len(c.n.keys) {
+			c.n, c.i = c.n.right, 0
+			continue
+		}
+		if _, ok := c.n.values[c.i].(tombstone); !ok {
+			return
+		}
+		c.i++
+	}
+}
+
+// backward moves the cursor to the last value of the previous key, skipping tombstones.
+func (c *Cursor[K, V]) backward() {
+	for c.n != nil {
+		c.i--
+		if c.i < 0 {
+			if c.n = c.n.left; c.n != nil {
+				c.i = len(c.n.keys)
+			}
+			continue
+		}
+		switch v := c.n.values[c.i].(type) {
+		case tombstone:
+			continue
+		case collision[V]:
+			c.ci = len(v) - 1
+		default:
+			c.ci = 0
+		}
+		return
+	}
+}
+
+// seekLE moves the cursor to the last pair with key less or equal to a given key and returns it.
+func (c *Cursor[K, V]) seekLE(key K) (K, V, bool) {
+	k, _, ok := c.Seek(key)
+	if !ok {
+		return c.Last()
+	}
+	if cmp.Compare(k, key) != 0 {
+		return c.Prev()
+	}
+	if col, ok := c.n.values[c.i].(collision[V]); ok {
+		c.ci = len(col) - 1
+	}
+	return c.current()
+}
+
+func (c *Cursor[K, V]) current() (key K, val V, ok bool) {
+	if c.n == nil || c.i >= len(c.n.keys) {
+		c.n = nil
+		return
+	}
+	v := c.n.values[c.i]
+	if col, ok := v.(collision[V]); ok {
+		return c.n.keys[c.i], col[c.ci], true
+	}
+	return c.n.keys[c.i], v.(V), true
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestCursor(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	c := t.Cursor()
+	if _, _, ok := c.First(); ok {
+		fail(T, t, "first found when tree is empty")
+	}
+	if _, _, ok := c.Last(); ok {
+		fail(T, t, "last found when tree is empty")
+	}
+	if _, _, ok := c.Seek(0); ok {
+		fail(T, t, "seek found when tree is empty")
+	}
+	keys, values := makeAppendKeysValues(numKeys)
+	for i, k := range keys {
+		t.Append(k*2, values[i])
+	}
+	entries := t.Entries()
+	i := 0
+	for k, v, ok := c.First(); ok; k, v, ok = c.Next() {
+		if k != entries[i].Key || v != entries[i].Value {
+			failf(T, t, "next: (%d, %d) != (%d, %d)", k, v, entries[i].Key, entries[i].Value)
+		}
+		i++
+	}
+	if i != len(entries) {
+		failf(T, t, "next: visited %d, needed %d", i, len(entries))
+	}
+	for k, v, ok := c.Last(); ok; k, v, ok = c.Prev() {
+		i--
+		if k != entries[i].Key || v != entries[i].Value {
+			failf(T, t, "prev: (%d, %d) != (%d, %d)", k, v, entries[i].Key, entries[i].Value)
+		}
+	}
+	if i != 0 {
+		failf(T, t, "prev: %d not visited", i)
+	}
+	for key := -1; key <= numKeys*2; key++ {
+		k, v, ok := c.Seek(key)
+		j := 0
+		for j < len(entries) && entries[j].Key < key {
+			j++
+		}
+		if j == len(entries) {
+			if ok {
+				failf(T, t, "seek(%d): found (%d, %d) after last key", key, k, v)
+			}
+			continue
+		}
+		if !ok || k != entries[j].Key || v != entries[j].Value {
+			failf(T, t, "seek(%d): (%d, %d, %v) != (%d, %d)", key, k, v, ok, entries[j].Key, entries[j].Value)
+		}
+		if j > 0 {
+			k, v, ok = c.Prev()
+			if !ok || k != entries[j-1].Key || v != entries[j-1].Value {
+				failf(T, t, "seek(%d) and prev: (%d, %d, %v) != (%d, %d)", key, k, v, ok, entries[j-1].Key, entries[j-1].Value)
+			}
+		}
+	}
+}
+
+func TestCursorModified(T *testing.T) {
+	t := NewBPTree[int, int](MinOrder)
+	for k := 0; k < numRangeTestKeys; k++ {
+		t.Insert(k, k)
+	}
+	c := t.Cursor()
+	var n int
+	for k, _, ok := c.Last(); ok; k, _, ok = c.Prev() {
+		n++
+		t.Delete(k)
+	}
+	if n != 1 || c.Err() != ErrModified {
+		failf(T, t, "%d pairs visited backward, error %v", n, c.Err())
+	}
+	n = 0
+	for k, _, ok := c.First(); ok; k, _, ok = c.Next() {
+		n++
+		t.Delete(k)
+	}
+	if n != 1 || c.Err() != ErrModified {
+		failf(T, t, "%d pairs visited forward, error %v", n, c.Err())
+	}
+	if _, _, ok := c.Seek(10); !ok || c.Err() != nil {
+		failf(T, t, "cursor is not reset, error %v", c.Err())
+	}
+	n = 0
+	t.Descend(func(kv KeyValue[int, int]) bool {
+		n++
+		t.Delete(kv.Key)
+		return true
+	})
+	if n != 1 {
+		failf(T, t, "%d pairs descended", n)
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// DeleteWhere removes key-value pairs from interval [*from; *to) for which pred returns true, and returns
+// the number of removed pairs. Nil given as a parameter will be interpreted as begin or end whole tree key
+// diapason. Pred is called for all pairs of interval in one pass before any of them is removed, so it must
+// not modify tree. If more than a quarter of pairs of tree is removed, tree is rebuilt bottom-up with fully
+// filled nodes instead of rebalancing it after every removal (unless mutations of tree are being recorded,
+// see Record).
+func (t *BPTree[K, V]) DeleteWhere(from *K, to *K, pred func(KeyValue[K, V]) bool) int {
+	type match struct {
+		key  K
+		idxs []int // indices of matching values, in ascending order
+	}
+	var matches []match
+	var count int
+	i := RangeIterator[K, V]{t: t}
+	i.Reset(from, to)
+SCAN:
+	for n := i.n; n != nil; n = n.right {
+		for j, key := range n.keys {
+			if from != nil && cmp.Less(key, *from) {
+				continue
+			}
+			if to != nil && !cmp.Less(key, *to) {
+				break SCAN
+			}
+			var idxs []int
+			switch v := n.values[j].(type) {
+			case tombstone:
+			case collision[V]:
+				for idx, val := range v {
+					if pred(KeyValue[K, V]{Key: key, Value: val}) {
+						idxs = append(idxs, idx)
+					}
+				}
+			default:
+				if pred(KeyValue[K, V]{Key: key, Value: v}) {
+					idxs = []int{0}
+				}
+			}
+			if len(idxs) != 0 {
+				matches = append(matches, match{key: key, idxs: idxs})
+				count += len(idxs)
+			}
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	if count*4 <= t.size || t.recorder != nil {
+		for _, m := range matches {
+			for j := len(m.idxs) - 1; j >= 0; j-- {
+				t.delete(m.key, false, m.idxs[j])
+			}
+		}
+		return count
+	}
+	t.withLabels("deletewhere", func() {
+		keys := make([]K, 0, t.size)
+		values := make([]any, 0, t.size)
+		mi := 0
+		for n := t.firstLeaf(); n != nil; n = n.right {
+			for j, v := range n.values {
+				if _, ok := v.(tombstone); ok {
+					continue
+				}
+				key := n.keys[j]
+				if mi < len(matches) && cmp.Compare(key, matches[mi].key) == 0 {
+					var ok bool
+					v, ok = t.without(key, v, matches[mi].idxs)
+					mi++
+					if !ok {
+						continue
+					}
+				}
+				keys = append(keys, key)
+				values = append(values, v)
+			}
+		}
+		t.root = buildTree[K, V](t.order(), keys, values).root
+		t.tombstones = 0
+		t.version++
+	})
+	t.size -= count
+	t.counters.Deletes += uint64(count)
+	return count
+}
+
+// without returns a value slot holding V or collision[V] without values at given indices in ascending order,
+// or false if no values are left.
+func (t *BPTree[K, V]) without(key K, v any, idxs []int) (any, bool) {
+	vals, ok := v.(collision[V])
+	if !ok {
+		vals = collision[V]{v.(V)}
+	}
+	var kept collision[V]
+	for idx, val := range vals {
+		if len(idxs) != 0 && idxs[0] == idx {
+			idxs = idxs[1:]
+			if t.memCostFn != nil {
+				t.memCost -= t.memCostFn(key, val)
+			}
+			continue
+		}
+		kept = append(kept, val)
+	}
+	switch len(kept) {
+	case 0:
+		return nil, false
+	case 1:
+		return kept[0], true
+	}
+	return kept, true
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeleteWhere(T *testing.T) {
+	keys, extra := genExtraKeys(numRangeTestKeys, numExtraKeys)
+	for _, every := range []int{1, 2, 7} { // 1 and 2 remove enough pairs to rebuild tree
+		for _, from := range extra {
+			for _, to := range extra {
+				t := NewBPTree[int, int](MinOrder)
+				t.SetMemoryCost(func(int, int) int64 { return 1 })
+				m := map[int][]int{}
+				for _, k := range keys {
+					for v := 0; v < k%3+1; v++ {
+						t.Append(k, v)
+						m[k] = append(m[k], v)
+					}
+				}
+				t.SetLazyDeletion(true)
+				t.DeleteAll(keys[len(keys)/2])
+				delete(m, keys[len(keys)/2])
+				pred := func(kv KeyValue[int, int]) bool { return (kv.Key+kv.Value.(int))%every == 0 }
+				needed := 0
+				for k, vals := range m {
+					if (from != nil && k < *from) || (to != nil && k >= *to) {
+						continue
+					}
+					var kept []int
+					for _, v := range vals {
+						if pred(KeyValue[int, int]{Key: k, Value: v}) {
+							needed++
+						} else {
+							kept = append(kept, v)
+						}
+					}
+					if len(kept) == 0 {
+						delete(m, k)
+					} else {
+						m[k] = kept
+					}
+				}
+				if count := t.DeleteWhere(from, to, pred); count != needed {
+					failf(T, t, "every %d in [%v; %v): %d removed, needed %d", every, from, to, count, needed)
+				}
+				compareWithMap(T, t, m)
+				if t.memCost != int64(t.Size()) {
+					failf(T, t, "memory cost %d, needed %d", t.memCost, t.Size())
+				}
+			}
+		}
+	}
+}
+
+func TestDeleteWhereRecord(T *testing.T) {
+	keys, values, t, _ := makeTreeAppend(T, bmax, numKeys)
+	_, _, t2, _ := makeTreeAppendWithKeysValues(T, bmax, keys, values)
+	var log bytes.Buffer
+	t.Record(&log)
+	t.DeleteWhere(nil, nil, func(kv KeyValue[int, int]) bool { return kv.Value.(int)%2 == 0 })
+	t.StopRecording()
+	if err := t2.Replay(&log); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t2, "Replay", t2.Entries(), t.Entries())
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDot writes the node structure of tree to w in the Graphviz DOT language. Internal nodes are drawn
+// as boxes of separator keys with edges to their children, and leaves as boxes of keys, where a key with
+// multiple values is followed by their number, chained by dashed edges to their right siblings.
+func (t *BPTree[K, V]) WriteDot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph bptree {")
+	fmt.Fprintln(bw, "\tnode [shape=record];")
+	ids := map[*node[K, V]]int{}
+	var leaves []string
+	var writeNode func(n *node[K, V]) int
+	writeNode = func(n *node[K, V]) int {
+		id := len(ids)
+		ids[n] = id
+		var fields []string
+		if n.isLeaf() {
+			for i, k := range n.keys {
+				switch v := n.values[i].(type) {
+				case tombstone:
+				case collision[V]:
+					fields = append(fields, dotEscape(fmt.Sprintf("%v (%d)", k, len(v))))
+				default:
+					fields = append(fields, dotEscape(fmt.Sprint(k)))
+				}
+			}
+			leaves = append(leaves, fmt.Sprintf("n%d", id))
+		} else {
+			for i := range n.children {
+				fields = append(fields, fmt.Sprintf("<c%d>", i))
+				if i < len(n.keys) {
+					fields = append(fields, dotEscape(fmt.Sprint(n.keys[i])))
+				}
+			}
+		}
+		fmt.Fprintf(bw, "\tn%d [label=\"%s\"];\n", id, strings.Join(fields, "|"))
+		for i, c := range n.children {
+			fmt.Fprintf(bw, "\tn%d:c%d -> n%d;\n", id, i, writeNode(c))
+		}
+		return id
+	}
+	writeNode(t.root)
+	for i := 1; i < len(leaves); i++ {
+		fmt.Fprintf(bw, "\t%s -> %s [style=dashed, constraint=false];\n", leaves[i-1], leaves[i])
+	}
+	fmt.Fprintf(bw, "\t{ rank=same; %s }\n", strings.Join(leaves, "; "))
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotEscape escapes characters having special meaning in record labels.
+func dotEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`{}|<>"\ `, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// String returns the node structure of tree, one node per line, indented by depth. Internal nodes
+// are printed as their separator keys in brackets, and leaves as their key-value pairs in parentheses.
+func (t *BPTree[K, V]) String() string {
+	var b strings.Builder
+	var writeNode func(n *node[K, V], depth int)
+	writeNode = func(n *node[K, V], depth int) {
+		b.WriteString(strings.Repeat("  ", depth))
+		for i, k := range n.keys {
+			if i != 0 {
+				b.WriteByte(' ')
+			}
+			if n.isInternal() {
+				fmt.Fprintf(&b, "[%v]", k)
+				continue
+			}
+			switch v := n.values[i].(type) {
+			case tombstone:
+				fmt.Fprintf(&b, "(%v: -)", k)
+			case collision[V]:
+				fmt.Fprintf(&b, "(%v:", k)
+				for j, v := range v {
+					if j != 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, " %v", v)
+				}
+				b.WriteByte(')')
+			default:
+				fmt.Fprintf(&b, "(%v: %v)", k, v)
+			}
+		}
+		b.WriteByte('\n')
+		for _, c := range n.children {
+			writeNode(c, depth+1)
+		}
+	}
+	writeNode(t.root, 0)
+	return b.String()
+}
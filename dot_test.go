@@ -0,0 +1,56 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDot(T *testing.T) {
+	t := NewBPTree[string, int](MinOrder)
+	for i, k := range []string{"a", "b", "c", "d", "e", "a|b"} {
+		t.Append(k, i)
+	}
+	t.Append("a", 10)
+	var buf bytes.Buffer
+	if err := t.WriteDot(&buf); err != nil {
+		T.Fatal(err)
+	}
+	dot := buf.String()
+	for _, s := range []string{"digraph bptree {", `a\ (2)`, `a\|b`, " -> ", "style=dashed", "rank=same"} {
+		if !strings.Contains(dot, s) {
+			failf(T, t, "%q is not found in:\n%s", s, dot)
+		}
+	}
+	if n := strings.Count(dot, "[label="); n != t.Stats().InternalNodes+t.Stats().LeafNodes {
+		failf(T, t, "%d nodes in:\n%s", n, dot)
+	}
+}
+
+func TestString(T *testing.T) {
+	t := NewBPTree[int, int](MinOrder)
+	for _, k := range []int{1, 2, 3, 4} {
+		t.Append(k, k*10)
+	}
+	t.Append(1, 11)
+	t.SetLazyDeletion(true)
+	t.Delete(3)
+	needed := "[3]\n  (1: 10, 11) (2: 20)\n  (3: -) (4: 40)\n"
+	if s := t.String(); s != needed {
+		failf(T, t, "%q, needed %q", s, needed)
+	}
+}
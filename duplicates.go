@@ -0,0 +1,216 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"container/heap"
+	"unsafe"
+)
+
+// DuplicateOrder defines in which order multiple values of the same key are taken.
+type DuplicateOrder int
+
+const (
+	OldestFirst DuplicateOrder = iota // values are taken in the order they were appended
+	NewestFirst                       // values appended last are taken first
+)
+
+// SetDeleteOrder sets which value Delete removes when a key has multiple values:
+// the last appended with NewestFirst (default), or the first appended with OldestFirst.
+func (t *BPTree[K, V]) SetDeleteOrder(order DuplicateOrder) {
+	t.deleteOldest = order == OldestFirst
+}
+
+// SetFindAllOrder sets the order of values returned by FindAll: insertion order with OldestFirst (default),
+// or reverse with NewestFirst. Iterators, Range and Cursor always return values in insertion order.
+func (t *BPTree[K, V]) SetFindAllOrder(order DuplicateOrder) {
+	t.findAllOrder = order
+}
+
+// FindAllIndexed calls f for every value of a given key with its index, until f returns false. Values are
+// passed in the order they were appended regardless of SetFindAllOrder, and indices are those taken by
+// DeleteOne and ReplaceOne. Returns false if key is not found.
+func (t *BPTree[K, V]) FindAllIndexed(key K, f func(idx int, val V) bool) bool {
+	v, ok := t.find(key)
+	if !ok {
+		return false
+	}
+	if c, ok := v.(collision[V]); ok {
+		for i, val := range c {
+			if !f(i, val) {
+				break
+			}
+		}
+	} else {
+		f(0, v.(V))
+	}
+	return true
+}
+
+// ReplaceOne replaces the value of a given key at index idx, as passed by FindAllIndexed, and returns
+// (old value, true), or (zero, false) if there is no such key or index.
+func (t *BPTree[K, V]) ReplaceOne(key K, idx int, val V) (old V, ok bool) {
+	n := t.seekLeaf(key)
+	i, found := n.search(key)
+	if !found || idx < 0 {
+		return
+	}
+	switch c := n.values[i].(type) {
+	case tombstone:
+		return
+	case collision[V]:
+		if idx >= len(c) {
+			return
+		}
+		old = c[idx]
+		c[idx] = t.replaced(key, idx, old, val)
+	default:
+		if idx > 0 {
+			return
+		}
+		old = c.(V)
+		n.values[i] = t.replaced(key, idx, old, val)
+	}
+	t.version++
+	return old, true
+}
+
+// DeleteValue removes the first appended value of a given key for which eq returns true, and returns
+// (value, true), or (zero, false) if there is no such key or value. Unlike DeleteOne, it does not depend
+// on positions of values, which change as other values of the key are appended or removed.
+func (t *BPTree[K, V]) DeleteValue(key K, eq func(val V) bool) (val V, ok bool) {
+	idx := -1
+	t.FindAllIndexed(key, func(i int, v V) bool {
+		if eq(v) {
+			idx = i
+			return false
+		}
+		return true
+	})
+	if idx < 0 {
+		return
+	}
+	return t.DeleteOne(key, idx)
+}
+
+// DuplicateStats describes how values are distributed over keys of a tree.
+type DuplicateStats struct {
+	Single   int `json:"single"`    // keys with a single value
+	UpTo10   int `json:"up_to_10"`  // keys with 2 to 10 values
+	UpTo100  int `json:"up_to_100"` // keys with 11 to 100 values
+	Over100  int `json:"over_100"`  // keys with more than 100 values
+	MaxCount int `json:"max_count"` // maximal number of values of a key
+
+	// CollisionBytes is memory held by value slices of keys with multiple values,
+	// including unused capacity and slice headers.
+	CollisionBytes int64 `json:"collision_bytes"`
+}
+
+// DuplicateStats walks over all leaves of tree and returns the distribution of values over keys.
+func (t *BPTree[K, V]) DuplicateStats() DuplicateStats {
+	var s DuplicateStats
+	var v V
+	for n := t.firstLeaf(); n != nil; n = n.right {
+		for _, val := range n.values {
+			count := 1
+			switch c := val.(type) {
+			case tombstone:
+				continue
+			case collision[V]:
+				count = len(c)
+				s.CollisionBytes += int64(cap(c))*int64(unsafe.Sizeof(v)) + int64(unsafe.Sizeof(c))
+			}
+			switch {
+			case count == 1:
+				s.Single++
+			case count <= 10:
+				s.UpTo10++
+			case count <= 100:
+				s.UpTo100++
+			default:
+				s.Over100++
+			}
+			s.MaxCount = max(s.MaxCount, count)
+		}
+	}
+	return s
+}
+
+// KeyCount is a key with its number of values.
+type KeyCount[K Key] struct {
+	Key   K
+	Count int
+}
+
+// TopDuplicated returns up to k keys with the most values, by descending number of values,
+// and keys with equal numbers in ascending order. Leaves are walked once.
+func (t *BPTree[K, V]) TopDuplicated(k int) []KeyCount[K] {
+	if k <= 0 {
+		return nil
+	}
+	// top is a min-heap by count, with greater keys of equal counts on top, so it holds the best k seen
+	top := make(keyCountHeap[K], 0, min(k, t.size))
+	for n := t.firstLeaf(); n != nil; n = n.right {
+		for i, val := range n.values {
+			kc := KeyCount[K]{Key: n.keys[i], Count: 1}
+			switch c := val.(type) {
+			case tombstone:
+				continue
+			case collision[V]:
+				kc.Count = len(c)
+			}
+			if len(top) < k {
+				heap.Push(&top, kc)
+			} else if kc.Count > top[0].Count {
+				top[0] = kc
+				heap.Fix(&top, 0)
+			}
+		}
+	}
+	result := make([]KeyCount[K], len(top))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(&top).(KeyCount[K])
+	}
+	return result
+}
+
+type keyCountHeap[K Key] []KeyCount[K]
+
+func (h keyCountHeap[K]) Len() int {
+	return len(h)
+}
+
+func (h keyCountHeap[K]) Less(i, j int) bool {
+	if h[i].Count != h[j].Count {
+		return h[i].Count < h[j].Count
+	}
+	return cmp.Less(h[j].Key, h[i].Key)
+}
+
+func (h keyCountHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *keyCountHeap[K]) Push(x any) {
+	*h = append(*h, x.(KeyCount[K]))
+}
+
+func (h *keyCountHeap[K]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
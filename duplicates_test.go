@@ -0,0 +1,196 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDuplicateOrder(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	for k := 0; k < numRangeTestKeys; k++ {
+		for v := 0; v < 4; v++ {
+			t.Append(k, v)
+		}
+	}
+	if vals, _ := t.FindAll(0); !slices.Equal(vals, []int{0, 1, 2, 3}) {
+		failf(T, t, "default FindAll order: %v", vals)
+	}
+	t.SetFindAllOrder(NewestFirst)
+	if vals, _ := t.FindAll(0); !slices.Equal(vals, []int{3, 2, 1, 0}) {
+		failf(T, t, "NewestFirst FindAll order: %v", vals)
+	}
+	if v, _ := t.Find(0); v != 0 {
+		failf(T, t, "FindAll order changed Find: %d", v)
+	}
+	if v, ok := t.Delete(0); !ok || v != 3 {
+		failf(T, t, "default Delete removed (%d, %v), needed newest", v, ok)
+	}
+	t.SetDeleteOrder(OldestFirst)
+	for _, needed := range []int{0, 1, 2} {
+		if v, ok := t.Delete(0); !ok || v != needed {
+			failf(T, t, "OldestFirst Delete removed (%d, %v), needed %d", v, ok, needed)
+		}
+	}
+	if _, ok := t.Find(0); ok {
+		fail(T, t, "key found after all values are deleted")
+	}
+	t.SetFindAllOrder(OldestFirst)
+	if vals, _ := t.FindAll(1); !slices.Equal(vals, []int{0, 1, 2, 3}) {
+		failf(T, t, "OldestFirst FindAll order: %v", vals)
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+
+	var zero BPTree[int, int]
+	if err := zero.UnmarshalJSON([]byte(`[{"key":1,"value":0},{"key":1,"value":1}]`)); err != nil {
+		T.Fatal(err)
+	}
+	if vals, _ := zero.FindAll(1); !slices.Equal(vals, []int{0, 1}) {
+		failf(T, &zero, "zero tree FindAll order: %v", vals)
+	}
+	if v, ok := zero.Delete(1); !ok || v != 1 {
+		failf(T, &zero, "zero tree Delete removed (%d, %v), needed newest", v, ok)
+	}
+}
+
+func TestDuplicateStats(T *testing.T) {
+	t := NewBPTree[int, int64](bmax)
+	if s := t.DuplicateStats(); s != (DuplicateStats{}) {
+		failf(T, t, "stats of empty tree: %+v", s)
+	}
+	for k, count := range []int{1, 1, 2, 10, 11, 100, 101, 1} {
+		for v := 0; v < count; v++ {
+			t.Append(k, int64(v))
+		}
+	}
+	t.SetLazyDeletion(true)
+	t.Delete(7)
+	s := t.DuplicateStats()
+	if s.Single != 2 || s.UpTo10 != 2 || s.UpTo100 != 2 || s.Over100 != 1 || s.MaxCount != 101 {
+		failf(T, t, "invalid stats: %+v", s)
+	}
+	if s.CollisionBytes < (2+10+11+100+101)*8 {
+		failf(T, t, "collision bytes %d are less than values size", s.CollisionBytes)
+	}
+}
+
+func TestTopDuplicated(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	counts := map[int]int{}
+	for _, k := range genKeys(numKeys) {
+		counts[k] = k*7%13 + 1
+		for v := 0; v < counts[k]; v++ {
+			t.Append(k, v)
+		}
+	}
+	var all []KeyCount[int]
+	for k, c := range counts {
+		all = append(all, KeyCount[int]{k, c})
+	}
+	slices.SortFunc(all, func(a, b KeyCount[int]) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return a.Key - b.Key
+	})
+	for _, k := range []int{1, 5, 100, numKeys, numKeys + 1} {
+		top := t.TopDuplicated(k)
+		if !slices.Equal(top, all[:min(k, len(all))]) {
+			failf(T, t, "top %d: %v, needed %v", k, top[:min(len(top), 5)], all[:min(k, 5)])
+		}
+	}
+	if t.TopDuplicated(0) != nil {
+		fail(T, t, "top 0 is not nil")
+	}
+}
+
+func TestFindAllIndexed(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	t.SetFindAllOrder(NewestFirst)
+	for k, vals := range m {
+		var got []int
+		t.FindAllIndexed(k, func(idx int, val int) bool {
+			if idx != len(got) {
+				failf(T, t, "key %d: index %d, needed %d", k, idx, len(got))
+			}
+			got = append(got, val)
+			return true
+		})
+		if !slices.Equal(got, vals) {
+			failf(T, t, "key %d: values %v, needed %v", k, got, vals)
+		}
+		calls := 0
+		t.FindAllIndexed(k, func(int, int) bool {
+			calls++
+			return false
+		})
+		if calls != 1 {
+			failf(T, t, "key %d: %d calls after stop", k, calls)
+		}
+	}
+	if t.FindAllIndexed(-1, func(int, int) bool { return true }) {
+		fail(T, t, "missing key found")
+	}
+}
+
+func TestReplaceOne(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	for k, vals := range m {
+		idx := len(vals) / 2
+		if old, ok := t.ReplaceOne(k, idx, -k); !ok || old != vals[idx] {
+			failf(T, t, "key %d: replaced (%d, %t), needed (%d, true)", k, old, ok, vals[idx])
+		}
+		vals[idx] = -k
+		if _, ok := t.ReplaceOne(k, len(vals), 0); ok {
+			failf(T, t, "key %d: replaced out of range index", k)
+		}
+		if _, ok := t.ReplaceOne(k, -1, 0); ok {
+			failf(T, t, "key %d: replaced negative index", k)
+		}
+	}
+	if _, ok := t.ReplaceOne(-1, 0, 0); ok {
+		fail(T, t, "missing key replaced")
+	}
+	compareWithMap(T, t, m)
+}
+
+func TestDeleteValue(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	for k, vals := range m {
+		needed := vals[len(vals)/2]
+		val, ok := t.DeleteValue(k, func(v int) bool { return v == needed })
+		if !ok || val != needed {
+			failf(T, t, "key %d: deleted (%d, %t), needed (%d, true)", k, val, ok, needed)
+		}
+		if vals = slices.Delete(vals, slices.Index(vals, needed), slices.Index(vals, needed)+1); len(vals) == 0 {
+			delete(m, k)
+		} else {
+			m[k] = vals
+		}
+		if _, ok := t.DeleteValue(k, func(int) bool { return false }); ok {
+			failf(T, t, "key %d: deleted value not matching", k)
+		}
+	}
+	if _, ok := t.DeleteValue(-1, func(int) bool { return true }); ok {
+		fail(T, t, "missing key deleted")
+	}
+	compareWithMap(T, t, m)
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
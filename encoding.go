@@ -0,0 +1,147 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"encoding/json"
+	"slices"
+)
+
+// gobTree is the form of a tree encoded with encoding/gob.
+type gobTree[K Key, V any] struct {
+	Order  int
+	Keys   []K
+	Values [][]V
+}
+
+// jsonPair is an element of the JSON array a tree is encoded to.
+type jsonPair[K Key, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// GobEncode implements gob.GobEncoder. Tree order, keys and values are encoded, so keys and values
+// must be encodable by gob.
+func (t *BPTree[K, V]) GobEncode() ([]byte, error) {
+	g := gobTree[K, V]{Order: t.order()}
+	for n := t.firstLeaf(); n != nil; n = n.right {
+		for i, key := range n.keys {
+			switch v := n.values[i].(type) {
+			case tombstone:
+				continue
+			case collision[V]:
+				g.Values = append(g.Values, v)
+			default:
+				g.Values = append(g.Values, []V{v.(V)})
+			}
+			g.Keys = append(g.Keys, key)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder. Tree content is replaced with the decoded one, built bottom-up
+// with fully filled nodes of the encoded order, while settings of tree are kept. A zero BPTree may
+// be decoded to.
+func (t *BPTree[K, V]) GobDecode(data []byte) error {
+	var g gobTree[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&g); err != nil {
+		return err
+	}
+	if len(g.Keys) != len(g.Values) || g.Order < MinOrder || g.Order > MaxOrder {
+		return ErrInvalidFormat
+	}
+	var keys []K
+	var values []V
+	for i, k := range g.Keys {
+		if len(g.Values[i]) == 0 || i > 0 && !cmp.Less(g.Keys[i-1], k) {
+			return ErrInvalidFormat
+		}
+		for _, v := range g.Values[i] {
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+	}
+	t.load(g.Order, keys, values)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Tree is encoded as an array of objects with "key" and "value"
+// fields in key order, where multiple values of a key are separate objects in the order they were appended.
+func (t *BPTree[K, V]) MarshalJSON() ([]byte, error) {
+	pairs := make([]jsonPair[K, V], 0, t.size)
+	c := t.Cursor()
+	for k, v, ok := c.First(); ok; k, v, ok = c.Next() {
+		pairs = append(pairs, jsonPair[K, V]{Key: k, Value: v})
+	}
+	return json.Marshal(pairs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Tree content is replaced with pairs decoded from an array
+// written by MarshalJSON, in any order, and built bottom-up with fully filled nodes, while settings of
+// tree are kept. Values of equal keys are stored in the order they are given. A zero BPTree may be
+// decoded to, with the automatically chosen order.
+func (t *BPTree[K, V]) UnmarshalJSON(data []byte) error {
+	var pairs []jsonPair[K, V]
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	slices.SortStableFunc(pairs, func(a, b jsonPair[K, V]) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	keys := make([]K, len(pairs))
+	values := make([]V, len(pairs))
+	for i, p := range pairs {
+		keys[i], values[i] = p.Key, p.Value
+	}
+	order := 0
+	if t.root != nil {
+		order = t.order()
+	}
+	t.load(order, keys, values)
+	return nil
+}
+
+// load replaces tree content with a tree of a given order built from pairs sorted by key.
+func (t *BPTree[K, V]) load(order int, keys []K, values []V) {
+	if t.noDuplicates {
+		keys, values = lastOfEqual(keys, values)
+	}
+	ukeys, uvalues := groupSorted(keys, values)
+	b := buildTree[K, V](order, ukeys, uvalues)
+	t.root, t.size, t.tombstones = b.root, b.size, 0
+	if t.memCostFn != nil {
+		t.memCost = 0
+		for i, k := range keys {
+			t.memCost += t.memCostFn(k, values[i])
+		}
+	}
+	t.counters.Inserts += uint64(b.size)
+	t.version++
+	if t.recorder != nil {
+		// the bulk-built content is logged as a Clear followed by appends, which replay to the same pairs
+		t.recorder.record(logRecord[K, V]{Op: recordClear})
+		for i, k := range keys {
+			t.recorder.record(logRecord[K, V]{Op: recordAppend, Key: k, Value: values[i]})
+		}
+	}
+}
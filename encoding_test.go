@@ -0,0 +1,96 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+type treeHolder struct {
+	Name string
+	Tree *BPTree[int, int]
+}
+
+func TestGob(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	deleted := t.Entries()[0].Key
+	t.DeleteAll(deleted)
+	delete(m, deleted)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(treeHolder{Name: "x", Tree: t}); err != nil {
+		T.Fatal(err)
+	}
+	var h treeHolder
+	if err := gob.NewDecoder(&buf).Decode(&h); err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, h.Tree, m)
+	if h.Tree.order() != bmax {
+		failf(T, h.Tree, "order %d, needed %d", h.Tree.order(), bmax)
+	}
+	if err := h.Tree.GobDecode([]byte("garbage")); err == nil {
+		fail(T, h.Tree, "garbage decoded")
+	}
+}
+
+func TestJSON(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	for _, k := range []int{3, 1, 2, 1} {
+		t.Append(k, k*10+t.Size())
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		T.Fatal(err)
+	}
+	needed := `[{"key":1,"value":11},{"key":1,"value":13},{"key":2,"value":22},{"key":3,"value":30}]`
+	if string(data) != needed {
+		failf(T, t, "encoded %s, needed %s", data, needed)
+	}
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	if data, err = json.Marshal(treeHolder{Name: "x", Tree: t}); err != nil {
+		T.Fatal(err)
+	}
+	var h treeHolder
+	if err := json.Unmarshal(data, &h); err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, h.Tree, m)
+	if err := h.Tree.UnmarshalJSON([]byte(`[{"key":2,"value":1},{"key":1,"value":2},{"key":2,"value":3}]`)); err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, h.Tree, map[int][]int{1: {2}, 2: {1, 3}})
+	if err := h.Tree.UnmarshalJSON([]byte(`{}`)); err == nil {
+		fail(T, h.Tree, "object decoded")
+	}
+}
+
+func TestGobInvalidOrder(T *testing.T) {
+	for _, order := range []int{-1, 0, MinOrder - 1, MaxOrder + 1, math.MaxInt32} {
+		var buf bytes.Buffer
+		g := gobTree[int, int]{Order: order, Keys: []int{1}, Values: [][]int{{1}}}
+		if err := gob.NewEncoder(&buf).Encode(&g); err != nil {
+			T.Fatal(err)
+		}
+		var t BPTree[int, int]
+		if err := t.GobDecode(buf.Bytes()); err != ErrInvalidFormat {
+			T.Fatalf("order %d: error %v, needed %v", order, err, ErrInvalidFormat)
+		}
+	}
+}
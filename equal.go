@@ -0,0 +1,45 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// Equal reports whether tree and other hold the same key-value pairs in the same order, regardless
+// of their structure. Values are compared with valueEq. Both trees are scanned in parallel, and the
+// scan stops at the first difference.
+func (t *BPTree[K, V]) Equal(other *BPTree[K, V], valueEq func(a, b V) bool) bool {
+	if t.size != other.size {
+		return false
+	}
+	i1 := RangeIterator[K, V]{t: t}
+	i1.Reset(nil, nil)
+	i2 := RangeIterator[K, V]{t: other}
+	i2.Reset(nil, nil)
+	for {
+		kv1, ok1 := i1.Next()
+		kv2, ok2 := i2.Next()
+		if ok1 != ok2 {
+			return false
+		}
+		if !ok1 {
+			return true
+		}
+		if cmp.Compare(kv1.Key, kv2.Key) != 0 || !valueEq(kv1.Value.(V), kv2.Value.(V)) {
+			return false
+		}
+	}
+}
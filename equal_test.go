@@ -0,0 +1,58 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEqual(T *testing.T) {
+	keys, values, t, _ := makeTreeAppend(T, bmax, numKeys)
+	eq := func(a, b int) bool { return a == b }
+	_, _, t2, _ := makeTreeAppendWithKeysValues(T, bmax/2, keys, values)
+	if !t.Equal(t2, eq) || !t2.Equal(t, eq) {
+		fail(T, t, "trees of different order are not equal")
+	}
+	if !t.Equal(t, eq) {
+		fail(T, t, "tree is not equal to itself")
+	}
+	kv, _ := t2.Last()
+	t2.SetLazyDeletion(true)
+	t2.DeleteOne(kv.Key, 0)
+	if t.Equal(t2, eq) {
+		fail(T, t, "trees of different size are equal")
+	}
+	t2.Append(kv.Key, kv.Value.(int)+1)
+	if t.Equal(t2, eq) {
+		fail(T, t, "trees with different values are equal")
+	}
+	if !t.Equal(t2, func(a, b int) bool { return true }) {
+		fail(T, t, "trees are not equal with lax value comparison")
+	}
+	if !NewBPTree[int, int](bmax).Equal(NewBPTree[int, int](bmax*2), eq) {
+		fail(T, t, "empty trees are not equal")
+	}
+}
+
+func TestEqualNaN(T *testing.T) {
+	t := NewBPTree[float64, int](bmax)
+	t.Insert(math.NaN(), 0)
+	t.Insert(1, 1)
+	eq := func(a, b int) bool { return a == b }
+	if !t.Equal(t, eq) || !t.Equal(t.Clone(), eq) {
+		T.Fatal("tree with NaN key is not equal to itself")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// ErrRecordTooLarge is returned by ExportStream if an encoded key or value does not fit a record.
+var ErrRecordTooLarge = errors.New("record field is too large")
+
+// ExportStream writes all key-value pairs of tree to w in key order, as a stream of records
+// without tree structure. A record is an encoded key and an encoded value, each prefixed with
+// its length as a 4-byte big-endian unsigned integer. Multiple values of a key are written
+// as separate records in the order they were appended.
+func (t *BPTree[K, V]) ExportStream(w io.Writer, encodeKey func(K) ([]byte, error), encodeValue func(V) ([]byte, error)) error {
+	bw := bufio.NewWriter(w)
+	c := t.Cursor()
+	for k, v, ok := c.First(); ok; k, v, ok = c.Next() {
+		kb, err := encodeKey(k)
+		if err != nil {
+			return err
+		}
+		vb, err := encodeValue(v)
+		if err != nil {
+			return err
+		}
+		if err := writeRecordField(bw, kb); err != nil {
+			return err
+		}
+		if err := writeRecordField(bw, vb); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func writeRecordField(w *bufio.Writer, b []byte) error {
+	if uint64(len(b)) > math.MaxUint32 {
+		return ErrRecordTooLarge
+	}
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+	if _, err := w.Write(l[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// ImportStream reads records written by ExportStream from r until EOF and appends them to tree.
+// If tree is empty and records are in key order, tree is built bottom-up with fully filled nodes
+// (unless mutations of tree are being recorded, see Record).
+// On error, no records are added.
+func (t *BPTree[K, V]) ImportStream(r io.Reader, decodeKey func([]byte) (K, error), decodeValue func([]byte) (V, error)) error {
+	br := bufio.NewReader(r)
+	var keys []K
+	var values []V
+	sorted := true
+	for {
+		kb, err := readRecordField(br, true)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		vb, err := readRecordField(br, false)
+		if err != nil {
+			return err
+		}
+		k, err := decodeKey(kb)
+		if err != nil {
+			return err
+		}
+		v, err := decodeValue(vb)
+		if err != nil {
+			return err
+		}
+		if len(keys) != 0 && cmp.Less(k, keys[len(keys)-1]) {
+			sorted = false
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if t.size != 0 || t.tombstones != 0 || !sorted || t.recorder != nil {
+		for i, k := range keys {
+			t.Append(k, values[i])
+		}
+		return nil
+	}
+	if t.noDuplicates {
+		keys, values = lastOfEqual(keys, values)
+	}
+	ukeys, uvalues := groupSorted(keys, values)
+	b := buildTree[K, V](t.order(), ukeys, uvalues)
+	t.root, t.size = b.root, b.size
+	if t.memCostFn != nil {
+		for i, k := range keys {
+			t.memCost += t.memCostFn(k, values[i])
+		}
+	}
+	t.counters.Inserts += uint64(b.size)
+	t.version++
+	return nil
+}
+
+// readRecordField reads a length-prefixed record field. It returns io.EOF only if first is set
+// and r is at EOF, i.e. at the beginning of a record.
+func readRecordField(r *bufio.Reader, first bool) ([]byte, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		if err == io.EOF && !first {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	// the field is read without preallocation, so a corrupted length can not cause a huge allocation
+	n := int64(binary.BigEndian.Uint32(l[:]))
+	b, err := io.ReadAll(io.LimitReader(r, n))
+	if err == nil && int64(len(b)) != n {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+func encodeIntKey(k int) ([]byte, error) {
+	return binary.AppendVarint(nil, int64(k)), nil
+}
+
+func decodeIntKey(b []byte) (int, error) {
+	k, n := binary.Varint(b)
+	if n != len(b) {
+		return 0, errors.New("invalid key")
+	}
+	return int(k), nil
+}
+
+func encodeIntValue(v int) ([]byte, error) {
+	return []byte(strconv.Itoa(v)), nil
+}
+
+func decodeIntValue(b []byte) (int, error) {
+	return strconv.Atoi(string(b))
+}
+
+func TestExportImportStream(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+	var buf bytes.Buffer
+	if err := t.ExportStream(&buf, encodeIntKey, encodeIntValue); err != nil {
+		T.Fatal(err)
+	}
+	data := buf.Bytes()
+	t2 := NewBPTree[int, int](bmax)
+	if err := t2.ImportStream(bytes.NewReader(data), decodeIntKey, decodeIntValue); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t2, "bulk import", t2.Entries(), t.Entries())
+	if err := t2.Validate(); err != nil {
+		failf(T, t2, "tree validation failed: %s", err)
+	}
+	if t2.Size() != t.Size() {
+		failf(T, t2, "size %d, needed %d", t2.Size(), t.Size())
+	}
+	if err := t2.ImportStream(bytes.NewReader(data), decodeIntKey, decodeIntValue); err != nil {
+		T.Fatal(err)
+	}
+	if t2.Size() != 2*t.Size() {
+		failf(T, t2, "size after second import %d, needed %d", t2.Size(), 2*t.Size())
+	}
+	if err := t2.Validate(); err != nil {
+		failf(T, t2, "tree validation failed: %s", err)
+	}
+	for _, n := range []int{1, 4, 5, len(data) - 1} {
+		t3 := NewBPTree[int, int](bmax)
+		err := t3.ImportStream(bytes.NewReader(data[:n]), decodeIntKey, decodeIntValue)
+		if err != io.ErrUnexpectedEOF || t3.Size() != 0 {
+			failf(T, t3, "import of %d bytes: %v, size %d", n, err, t3.Size())
+		}
+	}
+	failing := func(int) ([]byte, error) { return nil, io.ErrClosedPipe }
+	if err := t.ExportStream(io.Discard, encodeIntKey, failing); err != io.ErrClosedPipe {
+		failf(T, t, "export with failing encoder: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+// maxFreeNodes is a maximal number of nodes of each kind kept by tree for reuse.
+const maxFreeNodes = 64
+
+// allocLeaf returns an empty leaf node of a given size, reusing a node removed by a merge if there is one.
+func (t *BPTree[K, V]) allocLeaf(size int) *node[K, V] {
+	if l := len(t.freeLeaves); l != 0 && cap(t.freeLeaves[l-1].keys) == size {
+		n := t.freeLeaves[l-1]
+		t.freeLeaves[l-1] = nil
+		t.freeLeaves = t.freeLeaves[:l-1]
+		t.counters.NodeReuses++
+		return n
+	}
+	t.counters.NodeAllocs++
+	if t.keysOnly {
+		return newKeysLeafNode[K, V](size)
+	}
+	return newLeafNode[K, V](size)
+}
+
+// allocInternal is like allocLeaf for internal nodes.
+func (t *BPTree[K, V]) allocInternal(size int) *node[K, V] {
+	if l := len(t.freeInternals); l != 0 && cap(t.freeInternals[l-1].children) == size {
+		n := t.freeInternals[l-1]
+		t.freeInternals[l-1] = nil
+		t.freeInternals = t.freeInternals[:l-1]
+		t.counters.NodeReuses++
+		return n
+	}
+	t.counters.NodeAllocs++
+	return newInternalNode[K, V](size)
+}
+
+// freeNode empties a node removed from tree and keeps it for reuse, unless enough nodes are kept already
+// or reuse is disabled with WithFreeList.
+// References held by the node are cleared, so they do not retain keys, values and other nodes;
+// slots past the length are zero already, as vacated slots are cleared when they are vacated.
+func (t *BPTree[K, V]) freeNode(n *node[K, V]) {
+	if t.noFreeList {
+		return
+	}
+	clear(n.keys)
+	n.keys = n.keys[:0]
+	n.left, n.right, n.count = nil, nil, 0
+	if n.isLeaf() {
+		clear(n.values)
+		n.values = n.values[:0]
+		if len(t.freeLeaves) < maxFreeNodes {
+			t.freeLeaves = append(t.freeLeaves, n)
+		}
+		return
+	}
+	clear(n.children)
+	n.children = n.children[:0]
+	if len(t.freeInternals) < maxFreeNodes {
+		t.freeInternals = append(t.freeInternals, n)
+	}
+}
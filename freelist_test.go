@@ -0,0 +1,59 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestNodeReuse(T *testing.T) {
+	keys, values, t, m := makeTreeAppend(T, bmax, numKeys)
+	for _, k := range keys[:len(keys)/2] {
+		t.DeleteAll(k)
+		delete(m, k)
+	}
+	if len(t.freeLeaves) == 0 || len(t.freeLeaves) > maxFreeNodes || len(t.freeInternals) > maxFreeNodes {
+		failf(T, t, "%d free leaves, %d free internal nodes", len(t.freeLeaves), len(t.freeInternals))
+	}
+	for _, n := range append(t.freeLeaves, t.freeInternals...) {
+		for _, k := range n.keys[:cap(n.keys)] {
+			if k != 0 {
+				failf(T, t, "free node retains key %d", k)
+			}
+		}
+		for _, v := range n.values[:cap(n.values)] {
+			if v != nil {
+				failf(T, t, "free node retains value %v", v)
+			}
+		}
+		for _, c := range n.children[:cap(n.children)] {
+			if c != nil {
+				fail(T, t, "free node retains child")
+			}
+		}
+	}
+	allocs := t.Counters().NodeAllocs
+	for i, k := range keys[:len(keys)/2] {
+		t.Append(k, values[i])
+		m[k] = append(m[k], values[i])
+	}
+	compareWithMap(T, t, m)
+	if t.Counters().NodeReuses == 0 {
+		fail(T, t, "no nodes reused")
+	}
+	if t.Counters().NodeAllocs+t.Counters().NodeReuses <= allocs {
+		fail(T, t, "reused nodes are not counted")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"io"
+)
+
+// Command returns pending mutations of the batch encoded with encoding/gob as a deterministic command
+// for FSM.Apply. The batch is left unchanged; when the command is applied through a replicated log,
+// the batch should be discarded rather than flushed. Keys and values must be encodable by gob.
+func (b *Batch[K, V]) Command() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(b.Mutations()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FSM makes a tree the state machine of a replicated log, in the shape expected by Raft libraries
+// such as hashicorp/raft: log entries are commands produced by Batch.Command, and snapshots
+// are written and read as gob streams. FSM is not thread-safe, like the tree itself.
+type FSM[K Key, V any] struct {
+	t *BPTree[K, V]
+}
+
+// NewFSM returns a new FSM applying commands to a given tree.
+func NewFSM[K Key, V any](t *BPTree[K, V]) *FSM[K, V] {
+	return &FSM[K, V]{t: t}
+}
+
+// Apply decodes a command produced by Batch.Command and applies it to the tree, like Batch.Flush.
+// Tree is not modified if the command can not be decoded.
+func (f *FSM[K, V]) Apply(cmd []byte) error {
+	var ms []Mutation[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(cmd)).Decode(&ms); err != nil {
+		return err
+	}
+	f.t.applyMutations(ms)
+	return nil
+}
+
+// Snapshot returns a point-in-time snapshot of the tree. It copies all key-value pairs,
+// so the tree may be modified while the snapshot is persisted.
+func (f *FSM[K, V]) Snapshot() (*FSMSnapshot[K, V], error) {
+	var ms []Mutation[K, V]
+	c := f.t.Cursor()
+	for k, v, ok := c.First(); ok; k, v, ok = c.Next() {
+		if len(ms) == 0 || cmp.Compare(ms[len(ms)-1].Key, k) != 0 {
+			ms = append(ms, Mutation[K, V]{Key: k, Reset: true})
+		}
+		ms[len(ms)-1].Values = append(ms[len(ms)-1].Values, v)
+	}
+	return &FSMSnapshot[K, V]{ms: ms}, nil
+}
+
+// Restore replaces the content of the tree with a snapshot written by FSMSnapshot.Persist.
+// Tree is not modified if the snapshot can not be decoded.
+func (f *FSM[K, V]) Restore(r io.Reader) error {
+	var ms []Mutation[K, V]
+	if err := gob.NewDecoder(r).Decode(&ms); err != nil {
+		return err
+	}
+	f.t.Clear()
+	f.t.applyMutations(ms)
+	return nil
+}
+
+// FSMSnapshot is a snapshot of a tree returned by FSM.Snapshot.
+type FSMSnapshot[K Key, V any] struct {
+	ms []Mutation[K, V]
+}
+
+// Persist writes the snapshot to w.
+func (s *FSMSnapshot[K, V]) Persist(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(s.ms)
+}
+
+// Release drops the snapshot data.
+func (s *FSMSnapshot[K, V]) Release() {
+	s.ms = nil
+}
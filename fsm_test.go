@@ -0,0 +1,69 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFSM(T *testing.T) {
+	leader, t := NewBPTree[int, string](bmax), NewBPTree[int, string](bmax)
+	fsm := NewFSM(t)
+	keys := genKeys(numKeys)
+	for i := 0; i < len(keys); i += numRangeTestKeys {
+		b := leader.NewBatch()
+		for _, k := range keys[i : i+numRangeTestKeys] {
+			b.Append(k%(numKeys/2), valueForKey(k))
+			if k%7 == 0 {
+				b.Delete(k / 2)
+			}
+		}
+		cmd, err := b.Command()
+		if err != nil {
+			T.Fatal(err)
+		}
+		if b.Len() == 0 {
+			T.Fatal("batch is emptied by Command")
+		}
+		b.Flush()
+		if err := fsm.Apply(cmd); err != nil {
+			T.Fatal(err)
+		}
+	}
+	compareItems(T, t, "Apply", t.Entries(), leader.Entries())
+	if err := fsm.Apply([]byte("garbage")); err == nil {
+		fail(T, t, "garbage command is applied")
+	}
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		T.Fatal(err)
+	}
+	t.Insert(-1, "after snapshot")
+	var buf bytes.Buffer
+	if err := snap.Persist(&buf); err != nil {
+		T.Fatal(err)
+	}
+	snap.Release()
+	restored := NewBPTree[int, string](bmax)
+	restored.Insert(-2, "before restore")
+	if err := NewFSM(restored).Restore(&buf); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, restored, "Restore", restored.Entries(), leader.Entries())
+	if err := restored.Validate(); err != nil {
+		failf(T, restored, "tree validation failed: %s", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keybits transforms integers and floats into unsigned integers whose order matches the natural
+// order of values, for order-preserving key encodings of bptree and bptreekeycodec.
+package keybits
+
+import (
+	"math"
+)
+
+// Int flips the sign bit of a signed integer of a given size in bytes, so it compares as unsigned.
+// Int is its own inverse, up to sign extension of sizes less than 8.
+func Int(u uint64, size int) uint64 {
+	return u ^ (1 << (size*8 - 1))
+}
+
+// Float64 returns IEEE 754 bits of f transformed to compare as unsigned integers. NaN is ordered
+// before all other values, like in cmp.Compare, and -0 is equal to 0.
+func Float64(f float64) uint64 {
+	if f != f {
+		return 0
+	}
+	if f == 0 {
+		f = 0 // -0 is equal to 0
+	}
+	bits := math.Float64bits(f)
+	if bits>>63 != 0 {
+		return ^bits
+	}
+	return bits | 1<<63
+}
+
+// FromFloat64 is the inverse of Float64.
+func FromFloat64(bits uint64) float64 {
+	switch {
+	case bits == 0:
+		return math.NaN()
+	case bits>>63 != 0:
+		return math.Float64frombits(bits &^ (1 << 63))
+	default:
+		return math.Float64frombits(^bits)
+	}
+}
+
+// Float32 is like Float64 for float32.
+func Float32(f float32) uint32 {
+	if f != f {
+		return 0
+	}
+	if f == 0 {
+		f = 0 // -0 is equal to 0
+	}
+	bits := math.Float32bits(f)
+	if bits>>31 != 0 {
+		return ^bits
+	}
+	return bits | 1<<31
+}
+
+// FromFloat32 is the inverse of Float32.
+func FromFloat32(bits uint32) float32 {
+	switch {
+	case bits == 0:
+		return float32(math.NaN())
+	case bits>>31 != 0:
+		return math.Float32frombits(bits &^ (1 << 31))
+	default:
+		return math.Float32frombits(^bits)
+	}
+}
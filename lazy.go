@@ -0,0 +1,63 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+// tombstone marks a slot of a key removed by lazy deletion.
+type tombstone struct{}
+
+// SetLazyDeletion enables or disables lazy deletion. When enabled, removing the last value of a key
+// leaves a tombstone in its leaf instead of removing the key and rebalancing the tree, so deletions never
+// cause merges or borrows. Tombstones are invisible to all operations, are reused when the key is inserted
+// again, and are removed by Compact. Disabling lazy deletion does not remove existing tombstones.
+func (t *BPTree[K, V]) SetLazyDeletion(enabled bool) {
+	t.lazyDeletion = enabled
+}
+
+// Tombstones returns a number of tombstones left by lazy deletion.
+func (t *BPTree[K, V]) Tombstones() int {
+	return t.tombstones
+}
+
+// Compact removes all tombstones left by lazy deletion, rebuilding the tree bottom-up with fully filled nodes.
+// Does nothing if there are no tombstones.
+func (t *BPTree[K, V]) Compact() {
+	if t.tombstones == 0 {
+		return
+	}
+	t.withLabels("compact", func() {
+		keys := make([]K, 0, t.size)
+		values := make([]any, 0, t.size)
+		for n := t.firstLeaf(); n != nil; n = n.right {
+			for i, v := range n.values {
+				if _, ok := v.(tombstone); !ok {
+					keys = append(keys, n.keys[i])
+					values = append(values, v)
+				}
+			}
+		}
+		t.root = buildTree[K, V](t.order(), keys, values).root
+		t.tombstones = 0
+		t.version++
+	})
+}
+
+// firstLeaf returns the leftmost leaf node.
+func (t *BPTree[K, V]) firstLeaf() *node[K, V] {
+	n := t.root
+	for n.isInternal() {
+		n = n.children[0]
+	}
+	return n
+}
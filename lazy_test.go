@@ -0,0 +1,118 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"sort"
+	"testing"
+)
+
+func mapEntries(m map[int][]int) []KeyValue[int, int] {
+	var keys []int
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var entries []KeyValue[int, int]
+	for _, k := range keys {
+		for _, v := range m[k] {
+			entries = append(entries, KeyValue[int, int]{Key: k, Value: v})
+		}
+	}
+	return entries
+}
+
+func compareEntriesWithMap(T *testing.T, t *BPTree[int, int], m map[int][]int) {
+	needed := mapEntries(m)
+	compareItems(T, t, "Entries", t.Entries(), needed)
+	compareItems(T, t, "Descend", collectItems(t.Descend), reverseItems(needed))
+	f, fok := t.First()
+	l, lok := t.Last()
+	if len(needed) == 0 {
+		if fok || lok {
+			fail(T, t, "first or last found in empty tree")
+		}
+	} else if f != needed[0] || l != needed[len(needed)-1] {
+		failf(T, t, "first/last (%v, %v) != (%v, %v)", f, l, needed[0], needed[len(needed)-1])
+	}
+}
+
+func TestLazyDeletion(T *testing.T) {
+	keys, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	counters := t.Counters()
+	shuffleKeys(keys)
+	deleted := 0
+	for i, k := range keys[:len(keys)/2] {
+		mv, ok := m[k]
+		if !ok {
+			if _, ok := t.Delete(k); ok {
+				failf(T, t, "deleted key deleted again: %d", k)
+			}
+			continue
+		}
+		if i%2 == 0 {
+			m[k] = mv[:len(mv)-1]
+			t.Delete(k)
+		} else {
+			m[k] = nil
+			t.DeleteAll(k)
+		}
+		if len(m[k]) == 0 {
+			deleted++
+			delete(m, k)
+		}
+		if t.Tombstones() != deleted {
+			failf(T, t, "invalid number of tombstones: %d, must be %d", t.Tombstones(), deleted)
+		}
+		if i%100 == 0 {
+			compareWithMap(T, t, m)
+			compareEntriesWithMap(T, t, m)
+		}
+	}
+	if c := t.Counters(); c.Merges != counters.Merges || c.Borrows != counters.Borrows {
+		failf(T, t, "tree was rebalanced on lazy deletion: %+v", c)
+	}
+	compareWithMap(T, t, m)
+	compareEntriesWithMap(T, t, m)
+	if s := t.Stats(); s.Tombstones != deleted || s.Keys != len(m) {
+		failf(T, t, "invalid stats: %+v", s)
+	}
+	for _, k := range keys[:len(keys)/4] {
+		if _, ok := m[k]; !ok {
+			deleted--
+		}
+		t.Append(k, k)
+		m[k] = append(m[k], k)
+	}
+	if t.Tombstones() != deleted {
+		failf(T, t, "invalid number of tombstones after append: %d, must be %d", t.Tombstones(), deleted)
+	}
+	compareWithMap(T, t, m)
+	t.Compact()
+	if t.Tombstones() != 0 {
+		failf(T, t, "tombstones after compaction: %d", t.Tombstones())
+	}
+	compareWithMap(T, t, m)
+	compareEntriesWithMap(T, t, m)
+	for k := range m {
+		t.DeleteAll(k)
+	}
+	compareEntriesWithMap(T, t, nil)
+	t.Compact()
+	if !isEmpty(t) {
+		fail(T, t, "tree is not empty")
+	}
+}
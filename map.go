@@ -0,0 +1,89 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+// Map is an ordered map with API similar to the generic Map of github.com/tidwall/btree, backed by BPTree.
+// Every key holds a single value.
+type Map[K Key, V any] struct {
+	t *BPTree[K, V]
+}
+
+// NewMap returns a new Map. Order has the same meaning as for NewBPTree.
+func NewMap[K Key, V any](order int) *Map[K, V] {
+	return &Map[K, V]{t: NewBPTree[K, V](order)}
+}
+
+// Tree returns the underlying BPTree.
+func (m *Map[K, V]) Tree() *BPTree[K, V] {
+	return m.t
+}
+
+// Set puts a value for a given key and returns (previous value, true) if the key was present, or (zero, false).
+func (m *Map[K, V]) Set(key K, value V) (V, bool) {
+	prev, ok := m.t.Find(key)
+	m.t.Insert(key, value)
+	return prev, ok
+}
+
+// Get returns (value, true) for a given key, or (zero, false) if not found.
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	return m.t.Find(key)
+}
+
+// Delete removes a key and returns (value, true), or (zero, false) if not found.
+func (m *Map[K, V]) Delete(key K) (V, bool) {
+	return m.t.Delete(key)
+}
+
+// Len returns a number of keys in the map.
+func (m *Map[K, V]) Len() int {
+	return m.t.Size()
+}
+
+// Min returns (key, value, true) for the minimal key, or (zero, zero, false) if map is empty.
+func (m *Map[K, V]) Min() (K, V, bool) {
+	return m.t.Cursor().First()
+}
+
+// Max returns (key, value, true) for the maximal key, or (zero, zero, false) if map is empty.
+func (m *Map[K, V]) Max() (K, V, bool) {
+	return m.t.Cursor().Last()
+}
+
+// Scan calls iter for every key in ascending order until iter returns false.
+func (m *Map[K, V]) Scan(iter func(key K, value V) bool) {
+	m.t.Ascend(mapItemIterator(iter))
+}
+
+// Reverse calls iter for every key in descending order until iter returns false.
+func (m *Map[K, V]) Reverse(iter func(key K, value V) bool) {
+	m.t.Descend(mapItemIterator(iter))
+}
+
+// Ascend calls iter for every key greater or equal to pivot in ascending order until iter returns false.
+func (m *Map[K, V]) Ascend(pivot K, iter func(key K, value V) bool) {
+	m.t.AscendGreaterOrEqual(pivot, mapItemIterator(iter))
+}
+
+// Descend calls iter for every key less or equal to pivot in descending order until iter returns false.
+func (m *Map[K, V]) Descend(pivot K, iter func(key K, value V) bool) {
+	m.t.DescendLessOrEqual(pivot, mapItemIterator(iter))
+}
+
+func mapItemIterator[K Key, V any](iter func(key K, value V) bool) ItemIterator[K, V] {
+	return func(item KeyValue[K, V]) bool {
+		return iter(item.Key, item.Value.(V))
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestMap(T *testing.T) {
+	m := NewMap[int, string](bmax)
+	keys := genKeys(numKeys)
+	for _, k := range keys {
+		if _, ok := m.Set(k, "old"); ok {
+			fail(T, m.Tree(), "set: previous value found for new key")
+		}
+		if prev, ok := m.Set(k, valueForKey(k)); !ok || prev != "old" {
+			failf(T, m.Tree(), "set: previous value (%s, %v) != (old, true)", prev, ok)
+		}
+	}
+	if m.Len() != numKeys {
+		failf(T, m.Tree(), "invalid len: %d, must be %d", m.Len(), numKeys)
+	}
+	if k, v, ok := m.Min(); !ok || k != 0 || v != valueForKey(0) {
+		failf(T, m.Tree(), "min: (%d, %s, %v)", k, v, ok)
+	}
+	if k, v, ok := m.Max(); !ok || k != numKeys-1 || v != valueForKey(numKeys-1) {
+		failf(T, m.Tree(), "max: (%d, %s, %v)", k, v, ok)
+	}
+	next := 0
+	m.Scan(func(k int, v string) bool {
+		if k != next || v != valueForKey(k) {
+			failf(T, m.Tree(), "scan: (%d, %s), needed key %d", k, v, next)
+		}
+		next++
+		return true
+	})
+	if next != numKeys {
+		failf(T, m.Tree(), "scan: visited %d, needed %d", next, numKeys)
+	}
+	m.Reverse(func(k int, v string) bool {
+		next--
+		if k != next {
+			failf(T, m.Tree(), "reverse: key %d, needed %d", k, next)
+		}
+		return true
+	})
+	pivot := numKeys / 2
+	m.Ascend(pivot, func(k int, v string) bool {
+		if k != pivot {
+			failf(T, m.Tree(), "ascend: key %d, needed %d", k, pivot)
+		}
+		return false
+	})
+	m.Descend(pivot, func(k int, v string) bool {
+		if k != pivot {
+			failf(T, m.Tree(), "descend: key %d, needed %d", k, pivot)
+		}
+		return false
+	})
+	shuffleKeys(keys)
+	for _, k := range keys {
+		if v, ok := m.Delete(k); !ok || v != valueForKey(k) {
+			failf(T, m.Tree(), "delete: (%s, %v), needed %s", v, ok, valueForKey(k))
+		}
+		if _, ok := m.Get(k); ok {
+			failf(T, m.Tree(), "get: found after delete: %d", k)
+		}
+	}
+	if m.Len() != 0 {
+		failf(T, m.Tree(), "invalid len: %d, must be 0", m.Len())
+	}
+}
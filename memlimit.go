@@ -0,0 +1,110 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrMemoryLimit is returned by TryInsert and TryAppend if a new pair would exceed the memory limit of a tree.
+var ErrMemoryLimit = errors.New("tree memory limit exceeded")
+
+// SetMemoryLimit sets a limit in bytes for the estimated memory usage of a tree, see MemoryUsage.
+// The limit is checked by TryInsert and TryAppend only; Insert and Append always add pairs.
+// Zero or negative limit disables the check.
+func (t *BPTree[K, V]) SetMemoryLimit(bytes int64) {
+	t.memLimit = bytes
+}
+
+// MemoryUsage returns an estimated memory usage of a tree in bytes: the number of pairs times the size
+// of a key slot, a value slot and a value boxed into it, plus costs of pairs if a cost function is set
+// with SetMemoryCost. Node headers are not accounted.
+func (t *BPTree[K, V]) MemoryUsage() int64 {
+	return int64(t.size+t.tombstones)*pairSize[K, V]() + t.memCost
+}
+
+// SetMemoryCost sets a function returning memory held by a pair beyond its slots (e.g. the length
+// of a string or a byte slice value), which is added to MemoryUsage, so the memory limit is enforced
+// for values of varying size. The function is called for all pairs of tree when set, and for each
+// pair when it is added or removed; it must return the same cost for the same pair. Nil removes it.
+func (t *BPTree[K, V]) SetMemoryCost(cost func(key K, val V) int64) {
+	t.memCostFn, t.memCost = cost, 0
+	if cost == nil {
+		return
+	}
+	c := t.Cursor()
+	for k, v, ok := c.First(); ok; k, v, ok = c.Next() {
+		t.memCost += cost(k, v)
+	}
+}
+
+// valuesCost returns the cost of a value slot holding V or collision[V].
+func (t *BPTree[K, V]) valuesCost(key K, v any) int64 {
+	if c, ok := v.(collision[V]); ok {
+		var cost int64
+		for _, v := range c {
+			cost += t.memCostFn(key, v)
+		}
+		return cost
+	}
+	return t.memCostFn(key, v.(V))
+}
+
+func pairSize[K Key, V any]() int64 {
+	var k K
+	var v V
+	var slot any
+	return int64(unsafe.Sizeof(k) + unsafe.Sizeof(slot) + unsafe.Sizeof(v))
+}
+
+// TryInsert is like Insert, but returns ErrMemoryLimit without modifying tree if the pair
+// would increase memory usage over the memory limit.
+func (t *BPTree[K, V]) TryInsert(key K, val V) error {
+	if t.memLimit > 0 {
+		var delta int64
+		old, ok := t.find(key)
+		if !ok {
+			delta = pairSize[K, V]()
+		}
+		if t.memCostFn != nil {
+			delta += t.memCostFn(key, val)
+			if ok {
+				delta -= t.valuesCost(key, old)
+			}
+		}
+		if delta > 0 && t.MemoryUsage()+delta > t.memLimit {
+			return ErrMemoryLimit
+		}
+	}
+	t.Insert(key, val)
+	return nil
+}
+
+// TryAppend is like Append, but returns ErrMemoryLimit without modifying tree if a new pair
+// would exceed the memory limit.
+func (t *BPTree[K, V]) TryAppend(key K, val V) error {
+	if t.memLimit > 0 {
+		delta := pairSize[K, V]()
+		if t.memCostFn != nil {
+			delta += t.memCostFn(key, val)
+		}
+		if t.MemoryUsage()+delta > t.memLimit {
+			return ErrMemoryLimit
+		}
+	}
+	t.Append(key, val)
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestMemoryLimit(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	pair := pairSize[int, int]()
+	t.SetMemoryLimit(10 * pair)
+	for k := 0; k < 10; k++ {
+		if err := t.TryInsert(k, k); err != nil {
+			failf(T, t, "insert %d: %v", k, err)
+		}
+	}
+	if t.MemoryUsage() != 10*pair {
+		failf(T, t, "memory usage %d, needed %d", t.MemoryUsage(), 10*pair)
+	}
+	if err := t.TryInsert(10, 10); err != ErrMemoryLimit {
+		failf(T, t, "insert over limit: %v", err)
+	}
+	if err := t.TryAppend(0, 0); err != ErrMemoryLimit {
+		failf(T, t, "append over limit: %v", err)
+	}
+	if err := t.TryInsert(0, 100); err != nil {
+		failf(T, t, "replace at limit: %v", err)
+	}
+	if t.Size() != 10 {
+		failf(T, t, "size %d after rejected inserts", t.Size())
+	}
+	t.Delete(0)
+	if err := t.TryAppend(1, 1); err != nil {
+		failf(T, t, "append after delete: %v", err)
+	}
+	t.SetMemoryLimit(0)
+	if err := t.TryAppend(1, 1); err != nil {
+		failf(T, t, "append without limit: %v", err)
+	}
+}
+
+func TestMemoryCost(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	t.Insert(0, "abc")
+	pair := pairSize[int, string]()
+	t.SetMemoryCost(func(k int, v string) int64 { return int64(len(v)) })
+	if t.MemoryUsage() != pair+3 {
+		failf(T, t, "usage %d after setting cost", t.MemoryUsage())
+	}
+	t.SetMemoryLimit(3*pair + 10)
+	if err := t.TryAppend(0, "defgh"); err != nil {
+		failf(T, t, "append: %v", err)
+	}
+	if err := t.TryInsert(1, "ij"); err != nil {
+		failf(T, t, "insert: %v", err)
+	}
+	if t.MemoryUsage() != 3*pair+10 {
+		failf(T, t, "usage %d at limit", t.MemoryUsage())
+	}
+	if err := t.TryInsert(1, "ijk"); err != ErrMemoryLimit {
+		failf(T, t, "larger replace over limit: %v", err)
+	}
+	if err := t.TryInsert(1, "i"); err != nil || t.MemoryUsage() != 3*pair+9 {
+		failf(T, t, "smaller replace: %v, usage %d", err, t.MemoryUsage())
+	}
+	t.Delete(0)
+	if t.MemoryUsage() != 2*pair+4 {
+		failf(T, t, "usage %d after delete", t.MemoryUsage())
+	}
+	t.DeleteAll(0)
+	t.Insert(1, "long value")
+	if t.MemoryUsage() != pair+10 {
+		failf(T, t, "usage %d after replace", t.MemoryUsage())
+	}
+	t.Clear()
+	if t.MemoryUsage() != 0 {
+		failf(T, t, "usage %d after clear", t.MemoryUsage())
+	}
+	t.SetMemoryCost(nil)
+	t.Insert(0, "abc")
+	if t.MemoryUsage() != pair {
+		failf(T, t, "usage %d without cost", t.MemoryUsage())
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"errors"
+	"slices"
+)
+
+// ErrIndexExists is returned by AddIndex if MultiIndex has an index with the same name.
+var ErrIndexExists = errors.New("index already exists")
+
+// MultiIndex maintains several trees indexing the same set of values by different keys extracted from them,
+// keeping them consistent on insert and delete. Each index has its own key type and is added and queried
+// through an Index returned by AddIndex. Values are compared with == to find them on delete,
+// so pointers to records are a natural choice for V.
+type MultiIndex[V comparable] struct {
+	order   int
+	names   map[string]bool
+	indexes []indexOps[V]
+	size    int
+	pending []V // values inserted while there are no indexes, from which the first index is built
+}
+
+// indexOps maintains an Index regardless of its key type.
+type indexOps[V comparable] struct {
+	insert func(V)
+	// find returns a function deleting an occurrence of a value, or false if the value is not found
+	find func(V) (func(), bool)
+	// each calls f for every value of the index
+	each func(f func(V))
+}
+
+// Index is an index of MultiIndex with keys of type K.
+type Index[K Key, V comparable] struct {
+	extract func(V) K
+	t       *BPTree[K, V]
+}
+
+// NewMultiIndex returns a new MultiIndex without indexes. Order is used for all trees of MultiIndex
+// and has the same meaning as for NewBPTree.
+func NewMultiIndex[V comparable](order int) *MultiIndex[V] {
+	return &MultiIndex[V]{
+		order: order,
+		names: make(map[string]bool),
+	}
+}
+
+// AddIndex adds an index with a given name and key extractor to m, and builds it for already inserted values.
+// Returns ErrIndexExists if m has an index with the same name.
+func AddIndex[K Key, V comparable](m *MultiIndex[V], name string, extract func(V) K) (*Index[K, V], error) {
+	if m.names[name] {
+		return nil, ErrIndexExists
+	}
+	x := &Index[K, V]{
+		extract: extract,
+		t:       NewBPTree[K, V](m.order),
+	}
+	if len(m.indexes) != 0 {
+		m.indexes[0].each(func(v V) {
+			x.t.Append(extract(v), v)
+		})
+	} else {
+		for _, v := range m.pending {
+			x.t.Append(extract(v), v)
+		}
+		m.pending = nil
+	}
+	m.names[name] = true
+	m.indexes = append(m.indexes, indexOps[V]{
+		insert: func(v V) {
+			x.t.Append(x.extract(v), v)
+		},
+		find: func(v V) (func(), bool) {
+			key, idx := x.extract(v), -1
+			x.t.FindAllIndexed(key, func(i int, w V) bool {
+				if w == v {
+					idx = i
+				}
+				return idx < 0
+			})
+			if idx < 0 {
+				return nil, false
+			}
+			return func() { x.t.DeleteOne(key, idx) }, true
+		},
+		each: func(f func(V)) {
+			i := x.t.Iterator(nil, nil)
+			for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+				f(kv.Value.(V))
+			}
+		},
+	})
+	return x, nil
+}
+
+// Len returns a number of values in MultiIndex.
+func (m *MultiIndex[V]) Len() int {
+	return m.size
+}
+
+// Insert adds a value to all indexes. The same value may be inserted several times. Values inserted
+// before any index is added are kept until AddIndex builds the first index from them.
+func (m *MultiIndex[V]) Insert(val V) {
+	if len(m.indexes) == 0 {
+		m.pending = append(m.pending, val)
+	}
+	for _, x := range m.indexes {
+		x.insert(val)
+	}
+	m.size++
+}
+
+// Delete removes a value from all indexes and returns true, or false if the value is not found.
+// If the value was inserted several times, one occurrence is removed.
+func (m *MultiIndex[V]) Delete(val V) bool {
+	if len(m.indexes) == 0 {
+		i := slices.Index(m.pending, val)
+		if i < 0 {
+			return false
+		}
+		m.pending = slices.Delete(m.pending, i, i+1)
+		m.size--
+		return true
+	}
+	deletes := make([]func(), len(m.indexes))
+	for i, x := range m.indexes {
+		d, ok := x.find(val)
+		if !ok {
+			return false
+		}
+		deletes[i] = d
+	}
+	for _, d := range deletes {
+		d()
+	}
+	m.size--
+	return true
+}
+
+// Tree returns the tree of the index. The tree must not be modified directly.
+func (x *Index[K, V]) Tree() *BPTree[K, V] {
+	return x.t
+}
+
+// Find returns values with a given key.
+func (x *Index[K, V]) Find(key K) []V {
+	vals, _ := x.t.FindAll(key)
+	return vals
+}
+
+// Range returns values with keys from interval [*from; *to), ordered by key.
+// Nil given as from or to is interpreted as the beginning or the end of the index.
+func (x *Index[K, V]) Range(from, to *K) []V {
+	var vals []V
+	i := x.t.Iterator(from, to)
+	for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+		vals = append(vals, kv.Value.(V))
+	}
+	return vals
+}
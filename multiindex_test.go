@@ -0,0 +1,115 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"strconv"
+	"testing"
+)
+
+type multiIndexRecord struct {
+	name  string
+	group int
+}
+
+func TestMultiIndex(T *testing.T) {
+	m := NewMultiIndex[*multiIndexRecord](bmax)
+	byName, err := AddIndex(m, "name", func(r *multiIndexRecord) string { return r.name })
+	if err != nil {
+		T.Fatal(err)
+	}
+	if _, err := AddIndex(m, "name", func(r *multiIndexRecord) int { return r.group }); err != ErrIndexExists {
+		T.Fatalf("AddIndex with existing name: %v", err)
+	}
+	const numGroups = 10
+	records := make([]*multiIndexRecord, numKeys)
+	for i, k := range genKeys(numKeys) {
+		records[i] = &multiIndexRecord{name: strconv.Itoa(k), group: k % numGroups}
+		m.Insert(records[i])
+	}
+	byGroup, err := AddIndex(m, "group", func(r *multiIndexRecord) int { return r.group })
+	if err != nil {
+		T.Fatal(err)
+	}
+	check := func(deleted map[*multiIndexRecord]bool) {
+		if m.Len() != numKeys-len(deleted) {
+			T.Fatalf("invalid len: %d, must be %d", m.Len(), numKeys-len(deleted))
+		}
+		for _, r := range records {
+			vals := byName.Find(r.name)
+			if deleted[r] != (len(vals) == 0) || (len(vals) != 0 && vals[0] != r) {
+				T.Fatalf("invalid records with name %s: %v", r.name, vals)
+			}
+		}
+		for g := 0; g < numGroups; g++ {
+			for _, v := range byGroup.Find(g) {
+				if v.group != g || deleted[v] {
+					T.Fatalf("invalid record in group %d: %+v", g, v)
+				}
+			}
+		}
+		from, to := numGroups/2, numGroups
+		vals := byGroup.Range(&from, &to)
+		for i, v := range vals {
+			if v.group < from || (i > 0 && v.group < vals[i-1].group) {
+				T.Fatalf("invalid range of groups: %+v", v)
+			}
+		}
+	}
+	deleted := make(map[*multiIndexRecord]bool)
+	check(deleted)
+	for _, r := range records[:numKeys/2] {
+		if !m.Delete(r) {
+			T.Fatalf("record not deleted: %+v", r)
+		}
+		deleted[r] = true
+	}
+	if m.Delete(records[0]) {
+		T.Fatal("deleted record deleted again")
+	}
+	check(deleted)
+	if err := byName.Tree().Validate(); err != nil {
+		failf(T, byName.Tree(), "tree validation failed: %s", err)
+	}
+	if err := byGroup.Tree().Validate(); err != nil {
+		failf(T, byGroup.Tree(), "tree validation failed: %s", err)
+	}
+}
+
+func TestMultiIndexInsertBeforeIndex(T *testing.T) {
+	m := NewMultiIndex[*multiIndexRecord](bmax)
+	records := make([]*multiIndexRecord, numKeys)
+	for i, k := range genKeys(numKeys) {
+		records[i] = &multiIndexRecord{name: strconv.Itoa(k), group: k % 10}
+		m.Insert(records[i])
+	}
+	if !m.Delete(records[0]) || m.Delete(records[0]) {
+		T.Fatal("invalid delete without indexes")
+	}
+	byName, err := AddIndex(m, "name", func(r *multiIndexRecord) string { return r.name })
+	if err != nil {
+		T.Fatal(err)
+	}
+	byGroup, err := AddIndex(m, "group", func(r *multiIndexRecord) int { return r.group })
+	if err != nil {
+		T.Fatal(err)
+	}
+	if byName.Tree().Size() != numKeys-1 || byGroup.Tree().Size() != numKeys-1 || m.Len() != numKeys-1 {
+		T.Fatalf("sizes %d and %d, len %d, needed %d", byName.Tree().Size(), byGroup.Tree().Size(), m.Len(), numKeys-1)
+	}
+	if vals := byName.Find(records[1].name); len(vals) != 1 || vals[0] != records[1] {
+		T.Fatalf("record %s is not found", records[1].name)
+	}
+}
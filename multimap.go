@@ -0,0 +1,111 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"slices"
+)
+
+// MultiMap is a sorted multimap backed by BPTree: every key holds a list of values in the order they were added.
+type MultiMap[K Key, V comparable] struct {
+	t *BPTree[K, V]
+}
+
+// NewMultiMap returns a new MultiMap. Order has the same meaning as for NewBPTree.
+func NewMultiMap[K Key, V comparable](order int) *MultiMap[K, V] {
+	return &MultiMap[K, V]{t: NewBPTree[K, V](order)}
+}
+
+// Tree returns the underlying BPTree.
+func (m *MultiMap[K, V]) Tree() *BPTree[K, V] {
+	return m.t
+}
+
+// Len returns a number of key-value pairs in the multimap.
+func (m *MultiMap[K, V]) Len() int {
+	return m.t.Size()
+}
+
+// Add adds a value to the values of a key.
+func (m *MultiMap[K, V]) Add(key K, val V) {
+	m.t.Append(key, val)
+}
+
+// Get returns a copy of values of a key in the order they were added, or nil if the key is not present.
+func (m *MultiMap[K, V]) Get(key K) []V {
+	vals := m.values(key)
+	return slices.Clone(vals)
+}
+
+// values returns values of a key in the order they were added, regardless of the FindAll order of tree.
+func (m *MultiMap[K, V]) values(key K) []V {
+	v, ok := m.t.find(key)
+	if !ok {
+		return nil
+	}
+	if c, ok := v.(collision[V]); ok {
+		return c
+	}
+	return []V{v.(V)}
+}
+
+// Count returns a number of values of a key.
+func (m *MultiMap[K, V]) Count(key K) int {
+	vals := m.values(key)
+	return len(vals)
+}
+
+// Contains reports whether a key holds a given value.
+func (m *MultiMap[K, V]) Contains(key K, val V) bool {
+	vals := m.values(key)
+	return slices.Contains(vals, val)
+}
+
+// RemoveValue removes the first added occurrence of a value from the values of a key,
+// and reports whether it was found.
+func (m *MultiMap[K, V]) RemoveValue(key K, val V) bool {
+	vals := m.values(key)
+	i := slices.Index(vals, val)
+	if i < 0 {
+		return false
+	}
+	_, ok := m.t.DeleteOne(key, i)
+	return ok
+}
+
+// RemoveKey removes a key with all its values and returns them, or nil if the key is not present.
+func (m *MultiMap[K, V]) RemoveKey(key K) []V {
+	vals, _ := m.t.DeleteAll(key)
+	return vals
+}
+
+// Scan calls f for every key with its values in ascending key order until f returns false.
+// The values slice must not be retained or modified, and the multimap must not be modified by f.
+func (m *MultiMap[K, V]) Scan(f func(key K, vals []V) bool) {
+	var vals []V
+	c := m.t.Cursor()
+	k, v, ok := c.First()
+	for ok {
+		key := k
+		vals = vals[:0]
+		for ; ok && cmp.Compare(k, key) == 0; k, v, ok = c.Next() {
+			vals = append(vals, v)
+		}
+		if !f(key, vals) {
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMultiMap(T *testing.T) {
+	m := NewMultiMap[int, string](bmax)
+	t := m.Tree()
+	for k := 0; k < numRangeTestKeys; k++ {
+		for _, v := range []string{"a", "b", "a", "c"}[:k%4+1] {
+			m.Add(k, v)
+		}
+	}
+	if vals := m.Get(3); !slices.Equal(vals, []string{"a", "b", "a", "c"}) {
+		failf(T, t, "get 3: %v", vals)
+	}
+	if m.Get(-1) != nil || m.Count(-1) != 0 || m.Count(2) != 3 {
+		failf(T, t, "get/count: %v %d %d", m.Get(-1), m.Count(-1), m.Count(2))
+	}
+	if !m.Contains(1, "b") || m.Contains(1, "c") {
+		fail(T, t, "invalid Contains")
+	}
+	t.SetFindAllOrder(NewestFirst)
+	if !m.RemoveValue(3, "a") || !slices.Equal(m.Get(3), []string{"b", "a", "c"}) {
+		failf(T, t, "remove first a: %v", m.Get(3))
+	}
+	if m.RemoveValue(3, "x") || m.RemoveValue(-1, "a") {
+		fail(T, t, "absent value removed")
+	}
+	if !m.RemoveValue(0, "a") || m.Count(0) != 0 {
+		failf(T, t, "remove last value: %v", m.Get(0))
+	}
+	if vals := m.RemoveKey(1); !slices.Equal(vals, []string{"a", "b"}) || m.Count(1) != 0 {
+		failf(T, t, "remove key: %v", vals)
+	}
+	var keys, total int
+	m.Scan(func(key int, vals []string) bool {
+		if !slices.Equal(vals, m.Get(key)) {
+			failf(T, t, "scan %d: %v", key, vals)
+		}
+		keys++
+		total += len(vals)
+		return key < 10
+	})
+	if keys != 9 {
+		failf(T, t, "scan visited %d keys", keys)
+	}
+	total = 0
+	m.Scan(func(key int, vals []string) bool {
+		total += len(vals)
+		return true
+	})
+	if total != m.Len() {
+		failf(T, t, "scan visited %d values, len %d", total, m.Len())
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidOrder is returned by NewBPTreeFromOptions if order is out of [MinOrder; MaxOrder].
+var ErrInvalidOrder = errors.New("invalid tree order")
+
+// Options configures a tree created by NewBPTreeFromOptions. The zero value gives the same tree as NewBPTree(0).
+type Options struct {
+	// Order has the same meaning as for NewBPTree, 0 chooses it with AutoOrder.
+	Order int
+	// NoDuplicates makes Append replace values of present keys like Insert. Decoding and importing keep
+	// the last of values of a key then.
+	NoDuplicates bool
+	// NoFreeList disables reuse of nodes removed by merges, so they are left to the garbage collector.
+	NoFreeList bool
+}
+
+// Option is a functional option of NewBPTreeWithOptions.
+type Option func(*Options)
+
+// WithOrder sets the order of tree, see NewBPTree.
+func WithOrder(order int) Option {
+	return func(o *Options) { o.Order = order }
+}
+
+// WithAllowDuplicates sets whether Append keeps multiple values of a key (default), or replaces them like Insert.
+func WithAllowDuplicates(allow bool) Option {
+	return func(o *Options) { o.NoDuplicates = !allow }
+}
+
+// WithFreeList sets whether nodes removed by merges are kept for reuse by splits (default).
+func WithFreeList(enabled bool) Option {
+	return func(o *Options) { o.NoFreeList = !enabled }
+}
+
+// NewBPTreeFromOptions returns a new BPTree configured by opts. Unlike NewBPTree, which clamps order
+// to [MinOrder; MaxOrder], it returns ErrInvalidOrder if order is not 0 and is out of [MinOrder; MaxOrder].
+func NewBPTreeFromOptions[K Key, V any](opts Options) (*BPTree[K, V], error) {
+	if opts.Order != 0 && (opts.Order < MinOrder || opts.Order > MaxOrder) {
+		return nil, fmt.Errorf("%w: %d is out of [%d; %d]", ErrInvalidOrder, opts.Order, MinOrder, MaxOrder)
+	}
+	t := NewBPTree[K, V](opts.Order)
+	t.noDuplicates = opts.NoDuplicates
+	t.noFreeList = opts.NoFreeList
+	return t, nil
+}
+
+// NewBPTreeWithOptions is like NewBPTreeFromOptions with Options set by functional options.
+func NewBPTreeWithOptions[K Key, V any](opts ...Option) (*BPTree[K, V], error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return NewBPTreeFromOptions[K, V](o)
+}
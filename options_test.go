@@ -0,0 +1,120 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNewBPTreeFromOptions(T *testing.T) {
+	for _, order := range []int{-1, 1, MinOrder - 1, MaxOrder + 1} {
+		if t, err := NewBPTreeFromOptions[int, int](Options{Order: order}); !errors.Is(err, ErrInvalidOrder) {
+			T.Fatalf("order %d: tree %v, error %v", order, t, err)
+		}
+	}
+	for _, c := range []struct{ order, needed int }{{0, AutoOrder[int]()}, {MinOrder, MinOrder}, {bmax, bmax}} {
+		t, err := NewBPTreeFromOptions[int, int](Options{Order: c.order})
+		if err != nil {
+			T.Fatalf("order %d: %s", c.order, err)
+		}
+		if t.order() != c.needed {
+			failf(T, t, "order %d: tree order %d, needed %d", c.order, t.order(), c.needed)
+		}
+	}
+}
+
+func TestNewBPTreeWithOptions(T *testing.T) {
+	if _, err := NewBPTreeWithOptions[int, int](WithOrder(1)); !errors.Is(err, ErrInvalidOrder) {
+		T.Fatalf("order 1: error %v", err)
+	}
+	t, err := NewBPTreeWithOptions[int, int](WithOrder(MinOrder), WithAllowDuplicates(false), WithFreeList(false))
+	if err != nil {
+		T.Fatal(err)
+	}
+	if t.order() != MinOrder {
+		failf(T, t, "tree order %d, needed %d", t.order(), MinOrder)
+	}
+	for _, k := range genKeys(numKeys) {
+		t.Append(k, k)
+		t.Append(k, -k)
+	}
+	for k := 0; k < numKeys; k++ {
+		if vals, _ := t.FindAll(k); len(vals) != 1 || vals[0] != -k {
+			failf(T, t, "key %d: values %v, needed [%d]", k, vals, -k)
+		}
+	}
+	for k := 0; k < numKeys; k++ {
+		t.Delete(k)
+	}
+	if t.Size() != 0 || len(t.freeLeaves) != 0 || len(t.freeInternals) != 0 {
+		failf(T, t, "size %d, free nodes %d and %d", t.Size(), len(t.freeLeaves), len(t.freeInternals))
+	}
+	t, _ = NewBPTreeWithOptions[int, int]()
+	t.Append(1, 1)
+	t.Append(1, 2)
+	if t.Size() != 2 || t.order() != AutoOrder[int]() {
+		failf(T, t, "default options: size %d, order %d", t.Size(), t.order())
+	}
+}
+
+func TestNoDuplicatesBulk(T *testing.T) {
+	_, _, src, m := makeTreeAppend(T, bmax, numKeys)
+	// the last appended value of every key is kept
+	for k, vals := range m {
+		m[k] = vals[len(vals)-1:]
+	}
+	newTree := func() *BPTree[int, int] {
+		t, err := NewBPTreeWithOptions[int, int](WithOrder(bmax), WithAllowDuplicates(false))
+		if err != nil {
+			T.Fatal(err)
+		}
+		return t
+	}
+
+	t := newTree()
+	data, err := json.Marshal(src)
+	if err != nil {
+		T.Fatal(err)
+	}
+	if err = json.Unmarshal(data, t); err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, t, m)
+
+	t = newTree()
+	if data, err = src.GobEncode(); err != nil {
+		T.Fatal(err)
+	}
+	if err = t.GobDecode(data); err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, t, m)
+
+	t = newTree()
+	var buf bytes.Buffer
+	if err = src.ExportStream(&buf, encodeIntKey, encodeIntValue); err != nil {
+		T.Fatal(err)
+	}
+	if err = t.ImportStream(&buf, decodeIntKey, decodeIntValue); err != nil {
+		T.Fatal(err)
+	}
+	compareWithMap(T, t, m)
+	if t.Size() != len(m) {
+		failf(T, t, "size %d, needed %d", t.Size(), len(m))
+	}
+}
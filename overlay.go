@@ -0,0 +1,165 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// Overlay is an ordered map where writes go to a small mutable tree that is merged into a large
+// read-optimized base tree when it grows to a given threshold (or on Merge call). Base tree is rebuilt
+// bottom-up on every merge with fully filled nodes. Reads consult both trees. Every key holds a single value.
+type Overlay[K Key, V any] struct {
+	order     int
+	threshold int
+	base      *BPTree[K, V]
+	delta     *BPTree[K, overlayEntry[V]]
+	size      int
+}
+
+type overlayEntry[V any] struct {
+	val     V
+	deleted bool
+}
+
+// NewOverlay returns a new Overlay. Order has the same meaning as for NewBPTree and is used for both trees.
+// Writes are merged into the base tree when the number of keys written since the last merge reaches threshold;
+// threshold less or equal to zero disables automatic merging.
+func NewOverlay[K Key, V any](order, threshold int) *Overlay[K, V] {
+	return &Overlay[K, V]{
+		order:     order,
+		threshold: threshold,
+		base:      NewBPTree[K, V](order),
+		delta:     NewBPTree[K, overlayEntry[V]](order),
+	}
+}
+
+// Size returns a number of keys.
+func (o *Overlay[K, V]) Size() int {
+	return o.size
+}
+
+// Find returns (value, true) for a given key, or (zero, false) if not found.
+func (o *Overlay[K, V]) Find(key K) (V, bool) {
+	if e, ok := o.delta.Find(key); ok {
+		return e.val, !e.deleted
+	}
+	return o.base.Find(key)
+}
+
+// Insert puts a key-value pair, replacing the value if the key is present.
+func (o *Overlay[K, V]) Insert(key K, val V) {
+	if _, ok := o.Find(key); !ok {
+		o.size++
+	}
+	o.delta.Insert(key, overlayEntry[V]{val: val})
+	o.mergeIfNeeded()
+}
+
+// Delete removes a key and returns (value, true), or (zero, false) if not found.
+func (o *Overlay[K, V]) Delete(key K) (V, bool) {
+	val, ok := o.Find(key)
+	if !ok {
+		return val, false
+	}
+	o.size--
+	if _, ok := o.base.Find(key); ok {
+		o.delta.Insert(key, overlayEntry[V]{deleted: true})
+	} else {
+		o.delta.Delete(key)
+	}
+	o.mergeIfNeeded()
+	return val, true
+}
+
+func (o *Overlay[K, V]) mergeIfNeeded() {
+	if o.threshold > 0 && o.delta.Size() >= o.threshold {
+		o.Merge()
+	}
+}
+
+// Merge applies all writes to the base tree and rebuilds it.
+func (o *Overlay[K, V]) Merge() {
+	if o.delta.Size() == 0 {
+		return
+	}
+	keys := make([]K, 0, o.size)
+	values := make([]any, 0, o.size)
+	i := o.Iterator(nil, nil)
+	for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+		keys = append(keys, kv.Key)
+		values = append(values, kv.Value)
+	}
+	o.base = buildTree[K, V](o.order, keys, values)
+	o.delta.Clear()
+}
+
+// Iterator returns an Iterator for key-value pairs from interval [*from; *to). Nil given as a parameter will
+// be interpreted as begin or end whole key diapason. Iterator must not be used after Overlay is modified.
+func (o *Overlay[K, V]) Iterator(from, to *K) Iterator[K, V] {
+	i := &overlayIterator[K, V]{
+		base:  o.base.Iterator(from, to),
+		delta: o.delta.Iterator(from, to),
+	}
+	i.b, i.bok = i.base.Next()
+	i.d, i.dok = i.delta.Next()
+	return i
+}
+
+// Range returns a slice of key-value pairs from interval [*from; *to). Nil given as a parameter will
+// be interpreted as begin or end whole key diapason. If there are no keys found, returns nil.
+func (o *Overlay[K, V]) Range(from, to *K) []KeyValue[K, V] {
+	var result []KeyValue[K, V]
+	i := o.Iterator(from, to)
+	for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+		result = append(result, kv)
+	}
+	return result
+}
+
+type overlayIterator[K Key, V any] struct {
+	base  Iterator[K, V]
+	delta Iterator[K, overlayEntry[V]]
+	b     KeyValue[K, V]
+	d     KeyValue[K, overlayEntry[V]]
+	bok   bool
+	dok   bool
+}
+
+func (i *overlayIterator[K, V]) Next() (KeyValue[K, V], bool) {
+	for i.dok {
+		c := -1
+		if i.bok {
+			c = cmp.Compare(i.d.Key, i.b.Key)
+		}
+		if c > 0 {
+			break
+		}
+		if c == 0 {
+			i.b, i.bok = i.base.Next()
+		}
+		d := i.d
+		i.d, i.dok = i.delta.Next()
+		if e := d.Value.(overlayEntry[V]); !e.deleted {
+			return KeyValue[K, V]{Key: d.Key, Value: e.val}, true
+		}
+	}
+	if !i.bok {
+		return KeyValue[K, V]{}, false
+	}
+	b := i.b
+	i.b, i.bok = i.base.Next()
+	return b, true
+}
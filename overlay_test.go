@@ -0,0 +1,109 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBuildTree(T *testing.T) {
+	for _, order := range []int{MinOrder, 4, bmax} {
+		for n := 0; n < 3*order*order; n++ {
+			keys := make([]int, n)
+			values := make([]any, n)
+			for i := range keys {
+				keys[i], values[i] = i, valueForKey(i)
+			}
+			t := buildTree[int, string](order, keys, values)
+			if err := t.Validate(); err != nil {
+				failf(T, t, "tree validation failed: %s", err)
+			}
+			if t.Size() != n {
+				failf(T, t, "invalid size: %d, must be %d", t.Size(), n)
+			}
+			validateInsert(T, t, keys, n-1)
+		}
+	}
+}
+
+func TestOverlay(T *testing.T) {
+	o := NewOverlay[int, string](bmax, numKeys/10)
+	m := make(map[int]string)
+	for i := 0; i < numKeys*10; i++ {
+		k := rand.Intn(numKeys)
+		if rand.Intn(3) == 0 {
+			mv, mok := m[k]
+			v, ok := o.Delete(k)
+			if ok != mok || v != mv {
+				T.Fatalf("delete(%d): (%s, %v), needed (%s, %v)", k, v, ok, mv, mok)
+			}
+			delete(m, k)
+		} else {
+			v := valueForKey(i)
+			o.Insert(k, v)
+			m[k] = v
+		}
+		if o.Size() != len(m) {
+			T.Fatalf("invalid size: %d, must be %d", o.Size(), len(m))
+		}
+		if i%numKeys == 0 {
+			compareOverlay(T, o, m)
+		}
+	}
+	compareOverlay(T, o, m)
+	o.Merge()
+	if o.delta.Size() != 0 {
+		T.Fatal("delta is not empty after merge")
+	}
+	compareOverlay(T, o, m)
+	if err := o.base.Validate(); err != nil {
+		failf(T, o.base, "tree validation failed: %s", err)
+	}
+	if s := o.base.Stats(); s.LeafNodes > 2 && s.FillFactor < 0.9 {
+		failf(T, o.base, "base is not filled: %+v", s)
+	}
+}
+
+func compareOverlay(T *testing.T, o *Overlay[int, string], m map[int]string) {
+	var keys []int
+	for k, mv := range m {
+		keys = append(keys, k)
+		if v, ok := o.Find(k); !ok || v != mv {
+			T.Fatalf("find(%d): (%s, %v), needed %s", k, v, ok, mv)
+		}
+	}
+	sort.Ints(keys)
+	entries := o.Range(nil, nil)
+	if len(entries) != len(keys) {
+		T.Fatalf("invalid len(range): %d, must be %d", len(entries), len(keys))
+	}
+	for i, kv := range entries {
+		if kv.Key != keys[i] || kv.Value != m[keys[i]] {
+			T.Fatalf("range[%d]: (%d, %v), needed (%d, %s)", i, kv.Key, kv.Value, keys[i], m[keys[i]])
+		}
+	}
+	from, to := numKeys/4, numKeys/2
+	var n int
+	for _, k := range keys {
+		if k >= from && k < to {
+			n++
+		}
+	}
+	if r := o.Range(&from, &to); len(r) != n {
+		T.Fatalf("invalid len(range(%d, %d)): %d, must be %d", from, to, len(r), n)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// SetProfilerLabels enables pprof labels for bulk operations of a tree (Range, Entries, Ascend and Descend
+// methods, Stats), so CPU profiles attribute time to them. Labels are "bptree.op" with an operation name
+// and "bptree.size" with an order of magnitude of tree size (e.g. "1e3" for 1000..9999 pairs).
+// Labels are added to labels of a given parent context, and the goroutine labels are set back to them
+// after the operation, so parent should be the context the labels of calling goroutines come from
+// (or context.Background if they have none). Nil parent disables labels.
+func (t *BPTree[K, V]) SetProfilerLabels(parent context.Context) {
+	t.pprofCtx = parent
+}
+
+// withLabels runs f, with pprof labels of an operation if they are enabled.
+func (t *BPTree[K, V]) withLabels(op string, f func()) {
+	if t.pprofCtx == nil {
+		f()
+		return
+	}
+	pprof.Do(t.pprofCtx, pprof.Labels("bptree.op", op, "bptree.size", sizeBucket(t.size)), func(context.Context) {
+		f()
+	})
+}
+
+func sizeBucket(size int) string {
+	if size == 0 {
+		return "0"
+	}
+	return "1e" + strconv.Itoa(len(strconv.Itoa(size))-1)
+}
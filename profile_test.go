@@ -0,0 +1,45 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSizeBucket(T *testing.T) {
+	for size, bucket := range map[int]string{0: "0", 1: "1e0", 9: "1e0", 10: "1e1", 999: "1e2", 1000000: "1e6"} {
+		if b := sizeBucket(size); b != bucket {
+			T.Fatalf("sizeBucket(%d) = %s, needed %s", size, b, bucket)
+		}
+	}
+}
+
+func TestProfilerLabels(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	for _, k := range genKeys(numKeys) {
+		t.Insert(k, valueForKey(k))
+	}
+	entries := t.Entries()
+	t.SetProfilerLabels(context.Background())
+	compareItems(T, t, "Entries", t.Entries(), entries)
+	compareItems(T, t, "Ascend", collectItems(t.Ascend), entries)
+	compareItems(T, t, "Descend", collectItems(t.Descend), reverseItems(entries))
+	if s := t.Stats(); s.Size != numKeys {
+		failf(T, t, "invalid stats: %+v", s)
+	}
+	t.SetProfilerLabels(nil)
+	compareItems(T, t, "Entries", t.Entries(), entries)
+}
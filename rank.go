@@ -0,0 +1,76 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+// Rank returns a number of key-value pairs with keys less than a given key, i.e. the position in Entries
+// of the first pair with key, or where it would be inserted. It takes O(log n) time, since every node
+// keeps a number of pairs in its subtree.
+func (t *BPTree[K, V]) Rank(key K) int {
+	var rank int
+	n := t.root
+	for n.isInternal() {
+		i := n.childIndex(key)
+		for _, c := range n.children[:i] {
+			rank += c.count
+		}
+		n = n.children[i]
+	}
+	i, _ := n.search(key)
+	for _, v := range n.values[:i] {
+		rank += valueCount[V](v)
+	}
+	return rank
+}
+
+// Select returns (key-value, true) for the pair at index i in ascending order, i.e. Entries()[i],
+// or (zero, false) if i is out of range. Like Rank, it takes O(log n) time.
+func (t *BPTree[K, V]) Select(i int) (KeyValue[K, V], bool) {
+	if i < 0 || i >= t.size {
+		return KeyValue[K, V]{}, false
+	}
+	n := t.root
+	for n.isInternal() {
+		j := 0
+		for ; i >= n.children[j].count; j++ {
+			i -= n.children[j].count
+		}
+		n = n.children[j]
+	}
+	for j, v := range n.values {
+		count := valueCount[V](v)
+		if i >= count {
+			i -= count
+			continue
+		}
+		if c, ok := v.(collision[V]); ok {
+			return KeyValue[K, V]{Key: n.keys[j], Value: c[i]}, true
+		}
+		return KeyValue[K, V]{Key: n.keys[j], Value: v}, true
+	}
+	return KeyValue[K, V]{}, false
+}
+
+// CountRange returns a number of key-value pairs from interval [*from; *to) without iterating over them,
+// in O(log n) time. Nil given as a parameter will be interpreted as begin or end whole tree key diapason.
+func (t *BPTree[K, V]) CountRange(from *K, to *K) int {
+	lo, hi := 0, t.size
+	if from != nil {
+		lo = t.Rank(*from)
+	}
+	if to != nil {
+		hi = t.Rank(*to)
+	}
+	return max(hi-lo, 0)
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestRankSelect(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	for i, kv := range t.Entries() {
+		if i%3 == 0 {
+			t.DeleteAll(kv.Key)
+		}
+	}
+	entries := t.Entries()
+	for i, kv := range entries {
+		if s, ok := t.Select(i); !ok || s.Key != kv.Key || s.Value != kv.Value {
+			failf(T, t, "select %d: (%v, %t), needed %v", i, s, ok, kv)
+		}
+		if i == 0 || entries[i-1].Key != kv.Key {
+			if r := t.Rank(kv.Key); r != i {
+				failf(T, t, "rank of %d is %d, needed %d", kv.Key, r, i)
+			}
+		}
+	}
+	for _, k := range []int{-1, numKeys, 3} {
+		needed := 0
+		for _, kv := range entries {
+			if kv.Key < k {
+				needed++
+			}
+		}
+		if r := t.Rank(k); r != needed {
+			failf(T, t, "rank of %d is %d, needed %d", k, r, needed)
+		}
+	}
+	for _, i := range []int{-1, len(entries)} {
+		if _, ok := t.Select(i); ok {
+			failf(T, t, "select %d found", i)
+		}
+	}
+}
+
+func TestInsertReplacesValues(T *testing.T) {
+	t := NewBPTree[int, int](MinOrder)
+	for i := 0; i < 10; i++ {
+		t.Append(i%2, i)
+	}
+	t.Insert(0, -1)
+	if t.Size() != 6 {
+		failf(T, t, "size %d after replacement of 5 values, needed 6", t.Size())
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
+
+func TestCountRange(T *testing.T) {
+	keys, extra := genExtraKeys(numRangeTestKeys, numExtraKeys)
+	t := NewBPTree[int, int](MinOrder)
+	for _, k := range keys {
+		t.Append(k, k)
+		if k%2 == 0 {
+			t.Append(k, -k)
+		}
+	}
+	for _, from := range extra {
+		for _, to := range extra {
+			if count, needed := t.CountRange(from, to), len(t.Range(from, to)); count != needed {
+				failf(T, t, "count in [%v; %v) is %d, needed %d", from, to, count, needed)
+			}
+		}
+	}
+}
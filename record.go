@@ -0,0 +1,99 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+)
+
+const (
+	recordInsert = iota + 1
+	recordAppend
+	recordDelete
+	recordDeleteAll
+	recordClear
+	recordReplaceOne
+)
+
+// logRecord is a mutation of a tree written by Record.
+type logRecord[K Key, V any] struct {
+	Op    int
+	Key   K
+	Value V
+	Idx   int // index of a deleted or replaced value, -1 for the last one
+}
+
+type recorder[K Key, V any] struct {
+	enc *gob.Encoder
+	err error
+}
+
+// Record starts writing all following mutations of tree (inserts, deletes, replacements and Clear, including those
+// made by other methods through them) to w as a log encoded with encoding/gob, so keys and values
+// must be encodable by gob. Replay applies the log to another tree, which ends up with the same content
+// if it had the same content when recording started. Recording continues until StopRecording;
+// a previous recording is stopped.
+func (t *BPTree[K, V]) Record(w io.Writer) {
+	t.recorder = &recorder[K, V]{enc: gob.NewEncoder(w)}
+}
+
+// StopRecording stops recording started by Record and returns the first error writing the log, if any.
+func (t *BPTree[K, V]) StopRecording() error {
+	if t.recorder == nil {
+		return nil
+	}
+	err := t.recorder.err
+	t.recorder = nil
+	return err
+}
+
+func (r *recorder[K, V]) record(rec logRecord[K, V]) {
+	if r.err == nil {
+		r.err = r.enc.Encode(&rec)
+	}
+}
+
+// Replay applies mutations from a log written by Record to tree, until the end of the log.
+// Mutations read before an error are kept applied.
+func (t *BPTree[K, V]) Replay(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	for {
+		var rec logRecord[K, V]
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch rec.Op {
+		case recordInsert:
+			t.insert(rec.Key, rec.Value, true)
+		case recordAppend:
+			t.insert(rec.Key, rec.Value, false)
+		case recordDelete:
+			t.delete(rec.Key, false, rec.Idx)
+		case recordDeleteAll:
+			t.delete(rec.Key, true, 0)
+		case recordClear:
+			t.Clear()
+		case recordReplaceOne:
+			t.ReplaceOne(rec.Key, rec.Idx, rec.Value)
+		default:
+			return errors.New("invalid log record")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestRecordReplay(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	t.Insert(-1, "before recording")
+	t2 := NewBPTree[int, string](MinOrder)
+	t2.Insert(-1, "before recording")
+	var log bytes.Buffer
+	t.Record(&log)
+	keys := genKeys(numKeys)
+	for _, k := range keys {
+		t.Append(k%100, valueForKey(k))
+	}
+	t.SetDeleteOrder(OldestFirst)
+	for _, k := range keys[:100] {
+		t.Delete(k)
+	}
+	t.DeleteOne(5, 2)
+	t.DeleteAll(7)
+	t.ReplaceOne(9, 1, "replaced")
+	b := t.NewBatch()
+	b.Insert(8, "batch")
+	b.Flush()
+	if err := t.StopRecording(); err != nil {
+		T.Fatal(err)
+	}
+	t.Insert(1000, "after recording")
+	if err := t2.Replay(&log); err != nil {
+		T.Fatal(err)
+	}
+	t.Delete(1000)
+	compareItems(T, t2, "Replay", t2.Entries(), t.Entries())
+
+	var clearLog bytes.Buffer
+	t.Record(&clearLog)
+	t.Clear()
+	t.StopRecording()
+	if err := t2.Replay(&clearLog); err != nil || t2.Size() != 0 {
+		failf(T, t2, "replay of clear: %v, size %d", err, t2.Size())
+	}
+
+	var decodeLog bytes.Buffer
+	t2.Insert(9, "before decoding")
+	t.Record(&decodeLog)
+	if err := t.UnmarshalJSON([]byte(`[{"key":2,"value":"b"},{"key":1,"value":"a"},{"key":2,"value":"c"}]`)); err != nil {
+		T.Fatal(err)
+	}
+	t.StopRecording()
+	if err := t2.Replay(&decodeLog); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t2, "Replay of decoding", t2.Entries(), t.Entries())
+
+	t.Record(failingWriter{})
+	t.Insert(1, "x")
+	if err := t.StopRecording(); err == nil {
+		fail(T, t, "write error is not returned")
+	}
+	if err := t2.Replay(bytes.NewReader([]byte("garbage"))); err == nil {
+		fail(T, t2, "garbage log replayed")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// SafeIterator is an Iterator over key-value pairs of a tree from an interval, which may be used while
+// the tree is modified. It remembers the last returned key, and after a modification descends from the root
+// again to continue from the next greater key, so every key is visited at most once, and keys inserted
+// behind the iterator are not visited. If the tree is modified while the iterator is at a key with multiple
+// values, the rest of its values are skipped.
+type SafeIterator[K Key, V any] struct {
+	it      RangeIterator[K, V]
+	from    *K
+	to      *K
+	last    K
+	seek    K
+	started bool
+	done    bool
+}
+
+// NewSafeIterator returns a SafeIterator for key-value pairs from interval [*from; *to). Nil given as
+// a parameter will be interpreted as begin or end whole tree key diapason.
+func (t *BPTree[K, V]) NewSafeIterator(from *K, to *K) *SafeIterator[K, V] {
+	s := &SafeIterator[K, V]{it: RangeIterator[K, V]{t: t}, from: from, to: to}
+	s.it.Reset(from, to)
+	return s
+}
+
+func (s *SafeIterator[K, V]) Next() (KeyValue[K, V], bool) {
+	if s.done {
+		return KeyValue[K, V]{}, false
+	}
+	skip := false
+	if s.it.version != s.it.t.version {
+		if s.started {
+			s.seek = s.last
+			s.it.Reset(&s.seek, s.to)
+			skip = true
+		} else {
+			s.it.Reset(s.from, s.to)
+		}
+	}
+	kv, ok := s.it.Next()
+	for ok && skip && cmp.Compare(kv.Key, s.last) == 0 {
+		kv, ok = s.it.Next()
+	}
+	if !ok {
+		s.done = true
+		return KeyValue[K, V]{}, false
+	}
+	s.started, s.last = true, kv.Key
+	return kv, true
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestSafeIterator(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, MinOrder, numKeys)
+	visited := map[int]bool{}
+	iter := t.NewSafeIterator(nil, nil)
+	var prev int
+	for kv, ok := iter.Next(); ok; kv, ok = iter.Next() {
+		if visited[kv.Key] && kv.Key != prev {
+			failf(T, t, "key %d visited twice", kv.Key)
+		}
+		if _, ok := m[kv.Key]; !ok {
+			failf(T, t, "deleted key %d visited", kv.Key)
+		}
+		visited[kv.Key], prev = true, kv.Key
+		if kv.Key%3 == 0 {
+			t.DeleteAll(kv.Key)
+			delete(m, kv.Key)
+			t.Append(-kv.Key-1, 0) // behind the iterator
+		}
+		if next := kv.Key + 1; kv.Key%5 == 0 {
+			t.DeleteAll(next)
+			delete(m, next)
+		}
+	}
+	for k := range m {
+		if !visited[k] {
+			failf(T, t, "key %d not visited", k)
+		}
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
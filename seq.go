@@ -0,0 +1,73 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"iter"
+)
+
+// All returns an iterator over all key-value pairs of tree in ascending order, for use in range loops.
+// Multiple values of the same key are yielded one by one. Like RangeIterator and Cursor, iterators returned
+// by All, Backward, Between and BetweenBackward stop if tree is modified during the iteration.
+func (t *BPTree[K, V]) All() iter.Seq2[K, V] {
+	return t.Between(nil, nil)
+}
+
+// Backward is like All, but yields pairs in descending order, and multiple values of the same key
+// in reverse order.
+func (t *BPTree[K, V]) Backward() iter.Seq2[K, V] {
+	return t.BetweenBackward(nil, nil)
+}
+
+// Between returns an iterator over key-value pairs from interval [*from; *to) in ascending order.
+// Nil given as a parameter will be interpreted as begin or end whole tree key diapason.
+func (t *BPTree[K, V]) Between(from *K, to *K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		i := RangeIterator[K, V]{t: t}
+		i.Reset(from, to)
+		for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+			if !yield(kv.Key, kv.Value.(V)) {
+				return
+			}
+		}
+	}
+}
+
+// BetweenBackward is like Between, but yields pairs in descending order.
+func (t *BPTree[K, V]) BetweenBackward(from *K, to *K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c := t.Cursor()
+		var k K
+		var v V
+		var ok bool
+		if to == nil {
+			k, v, ok = c.Last()
+		} else {
+			k, v, ok = c.seekLE(*to)
+		}
+		for ; ok; k, v, ok = c.Prev() {
+			if to != nil && !cmp.Less(k, *to) {
+				continue
+			}
+			if from != nil && cmp.Less(k, *from) {
+				return
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func collectSeq(seq func(yield func(int, int) bool)) (items []KeyValue[int, int]) {
+	for k, v := range seq {
+		items = append(items, KeyValue[int, int]{Key: k, Value: v})
+	}
+	return items
+}
+
+func TestSeq(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+	compareItems(T, t, "All", collectSeq(t.All()), t.Entries())
+	compareItems(T, t, "Backward", collectSeq(t.Backward()), reverseItems(t.Entries()))
+	n := 0
+	for range t.All() {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		failf(T, t, "%d pairs iterated before break", n)
+	}
+}
+
+func TestSeqBetween(T *testing.T) {
+	keys, extra := genExtraKeys(numRangeTestKeys, numExtraKeys)
+	t := NewBPTree[int, int](MinOrder)
+	for _, k := range keys {
+		t.Append(k, k)
+		t.Append(k, -k)
+	}
+	for _, from := range extra {
+		for _, to := range extra {
+			needed := t.Range(from, to)
+			compareItems(T, t, "Between", collectSeq(t.Between(from, to)), needed)
+			compareItems(T, t, "BetweenBackward", collectSeq(t.BetweenBackward(from, to)), reverseItems(needed))
+		}
+	}
+}
+
+func TestSeqModified(T *testing.T) {
+	for _, backward := range []bool{false, true} {
+		t := NewBPTree[int, int](MinOrder)
+		for k := 0; k < numRangeTestKeys; k++ {
+			t.Insert(k, k)
+		}
+		seq := t.All()
+		if backward {
+			seq = t.Backward()
+		}
+		n := 0
+		for k := range seq {
+			n++
+			t.Delete(k)
+		}
+		if n != 1 {
+			failf(T, t, "backward %t: %d pairs iterated", backward, n)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// BPSet is a sorted set of keys backed by the nodes of BPTree, whose leaves are specialized to hold keys
+// without value slots, taking about half the memory of a tree with empty values.
+type BPSet[K Key] struct {
+	t *BPTree[K, struct{}] // has keys only, so only the operations used by BPSet apply to it
+}
+
+// NewBPSet returns a new BPSet. Order has the same meaning as for NewBPTree.
+func NewBPSet[K Key](order int) *BPSet[K] {
+	t := NewBPTree[K, struct{}](order)
+	t.keysOnly = true
+	t.root = newKeysLeafNode[K, struct{}](cap(t.root.keys))
+	return &BPSet[K]{t: t}
+}
+
+// Len returns a number of keys in the set.
+func (s *BPSet[K]) Len() int {
+	return s.t.Size()
+}
+
+// Insert adds a key to the set and reports whether it was not present.
+func (s *BPSet[K]) Insert(key K) bool {
+	size := s.t.size
+	s.t.Insert(key, struct{}{})
+	return s.t.size != size
+}
+
+// Delete removes a key from the set and reports whether it was present.
+func (s *BPSet[K]) Delete(key K) bool {
+	_, ok := s.t.Delete(key)
+	return ok
+}
+
+// Contains reports whether a key is present in the set.
+func (s *BPSet[K]) Contains(key K) bool {
+	return s.t.Contains(key)
+}
+
+// Range returns keys from interval [*from; *to) in ascending order. Nil given as a parameter will be
+// interpreted as begin or end whole set key diapason.
+func (s *BPSet[K]) Range(from, to *K) []K {
+	var keys []K
+	n, i := s.t.firstLeaf(), 0
+	if from != nil {
+		n = s.t.seekLeaf(*from)
+		i, _ = n.search(*from)
+	}
+	for ; n != nil; n, i = n.right, 0 {
+		for _, k := range n.keys[i:] {
+			if to != nil && !cmp.Less(k, *to) {
+				return keys
+			}
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Union returns a new set of the same order with keys present in either s or other.
+func (s *BPSet[K]) Union(other *BPSet[K]) *BPSet[K] {
+	a, b := s.Range(nil, nil), other.Range(nil, nil)
+	keys := make([]K, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch c := cmp.Compare(a[0], b[0]); {
+		case c < 0:
+			keys, a = append(keys, a[0]), a[1:]
+		case c > 0:
+			keys, b = append(keys, b[0]), b[1:]
+		default:
+			keys, a, b = append(keys, a[0]), a[1:], b[1:]
+		}
+	}
+	keys = append(append(keys, a...), b...)
+	return s.build(keys)
+}
+
+// Intersect returns a new set of the same order with keys present in both s and other.
+func (s *BPSet[K]) Intersect(other *BPSet[K]) *BPSet[K] {
+	a, b := s.Range(nil, nil), other.Range(nil, nil)
+	var keys []K
+	for len(a) > 0 && len(b) > 0 {
+		switch c := cmp.Compare(a[0], b[0]); {
+		case c < 0:
+			a = a[1:]
+		case c > 0:
+			b = b[1:]
+		default:
+			keys, a, b = append(keys, a[0]), a[1:], b[1:]
+		}
+	}
+	return s.build(keys)
+}
+
+// build returns a new set of the same order as s built bottom-up from sorted unique keys.
+func (s *BPSet[K]) build(keys []K) *BPSet[K] {
+	set := NewBPSet[K](s.t.order())
+	fillTree(set.t, keys, nil)
+	set.t.counters.Inserts = uint64(set.t.size)
+	return set
+}
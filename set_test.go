@@ -0,0 +1,102 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestBPSet(T *testing.T) {
+	s := NewBPSet[int](MinOrder)
+	t := s.t
+	for _, k := range genKeys(numKeys) {
+		if !s.Insert(k) || s.Insert(k) {
+			failf(T, t, "insert %d", k)
+		}
+	}
+	for k := 0; k < numKeys; k += 2 {
+		if !s.Delete(k) || s.Delete(k) {
+			failf(T, t, "delete %d", k)
+		}
+	}
+	if s.Len() != numKeys/2 || !s.Contains(1) || s.Contains(2) {
+		failf(T, t, "len %d, contains 1: %t, contains 2: %t", s.Len(), s.Contains(1), s.Contains(2))
+	}
+	from, to := 10, 20
+	if keys := s.Range(&from, &to); !slices.Equal(keys, []int{11, 13, 15, 17, 19}) {
+		failf(T, t, "range [%d; %d): %v", from, to, keys)
+	}
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+}
+
+func TestBPSetUnionIntersect(T *testing.T) {
+	a, b := NewBPSet[int](MinOrder), NewBPSet[int](bmax)
+	var union, intersection []int
+	for k := 0; k < numKeys; k++ {
+		inA, inB := k%2 == 0, k%3 == 0
+		if inA {
+			a.Insert(k)
+		}
+		if inB {
+			b.Insert(k)
+		}
+		if inA || inB {
+			union = append(union, k)
+		}
+		if inA && inB {
+			intersection = append(intersection, k)
+		}
+	}
+	for _, c := range []struct {
+		name   string
+		s      *BPSet[int]
+		needed []int
+	}{
+		{"union", a.Union(b), union},
+		{"intersection", a.Intersect(b), intersection},
+		{"empty intersection", a.Intersect(NewBPSet[int](bmax)), nil},
+	} {
+		t := c.s.t
+		if keys := c.s.Range(nil, nil); !slices.Equal(keys, c.needed) || c.s.Len() != len(c.needed) {
+			failf(T, t, "%s: %d keys, needed %d", c.name, c.s.Len(), len(c.needed))
+		}
+		if err := t.Validate(); err != nil {
+			failf(T, t, "%s: tree validation failed: %s", c.name, err)
+		}
+	}
+}
+
+func TestBPSetMemory(T *testing.T) {
+	keys := genKeys(numKeys)
+	allocated := func(insert func(k int)) uint64 {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for _, k := range keys {
+			insert(k)
+		}
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+	s, t := NewBPSet[int](bmax), NewBPTree[int, struct{}](bmax)
+	setBytes := allocated(func(k int) { s.Insert(k) })
+	treeBytes := allocated(func(k int) { t.Insert(k, struct{}{}) })
+	if setBytes*2 > treeBytes {
+		failf(T, s.t, "set allocated %d bytes, tree with empty values %d", setBytes, treeBytes)
+	}
+}
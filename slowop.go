@@ -0,0 +1,79 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"time"
+)
+
+// SlowOp describes an operation reported to a hook set by SetSlowOpHook.
+type SlowOp[K Key] struct {
+	Op       string        // "insert", "append", "delete", "range" or "values"
+	From, To *K            // key for insert, append and delete; interval for range and values, nil if open
+	Duration time.Duration // time spent in the operation
+	Nodes    int           // nodes touched: visited leaves or descent path, plus nodes split, merged or balanced
+}
+
+type slowOpHook[K Key] struct {
+	threshold time.Duration
+	maxNodes  int
+	fn        func(SlowOp[K])
+}
+
+// SetSlowOpHook sets fn to be called synchronously after an insert, append, delete, Range or
+// ValuesInRange operation which takes longer than threshold or touches more than maxNodes nodes.
+// Zero threshold or maxNodes disables the corresponding check, nil fn removes the hook.
+// While a hook is set, each operation reads the clock twice, so it should not be kept on
+// in latency-critical code without need.
+func (t *BPTree[K, V]) SetSlowOpHook(threshold time.Duration, maxNodes int, fn func(SlowOp[K])) {
+	if fn == nil {
+		t.slowOps = nil
+		return
+	}
+	t.slowOps = &slowOpHook[K]{threshold: threshold, maxNodes: maxNodes, fn: fn}
+}
+
+func (t *BPTree[K, V]) restructures() uint64 {
+	return t.counters.Splits + t.counters.Merges + t.counters.Borrows
+}
+
+// observePoint reports an operation on a single key started at start, when restructures were r.
+func (t *BPTree[K, V]) observePoint(op string, key K, start time.Time, r uint64) {
+	h := t.slowOps
+	if h == nil {
+		return
+	}
+	d := time.Since(start)
+	nodes := t.Height() + int(t.restructures()-r)
+	if h.exceeded(d, nodes) {
+		h.fn(SlowOp[K]{Op: op, From: &key, To: &key, Duration: d, Nodes: nodes})
+	}
+}
+
+// observeRange reports an operation on interval [*from; *to) started at start.
+func (t *BPTree[K, V]) observeRange(op string, from, to *K, start time.Time, nodes int) {
+	h := t.slowOps
+	if h == nil {
+		return
+	}
+	d := time.Since(start)
+	if h.exceeded(d, nodes) {
+		h.fn(SlowOp[K]{Op: op, From: from, To: to, Duration: d, Nodes: nodes})
+	}
+}
+
+func (h *slowOpHook[K]) exceeded(d time.Duration, nodes int) bool {
+	return (h.threshold > 0 && d > h.threshold) || (h.maxNodes > 0 && nodes > h.maxNodes)
+}
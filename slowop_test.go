@@ -0,0 +1,76 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowOpHook(T *testing.T) {
+	t := NewBPTree[int, int](4)
+	var ops []SlowOp[int]
+	t.SetSlowOpHook(0, 0, func(op SlowOp[int]) {
+		ops = append(ops, op)
+	})
+	keys := genKeys(numKeys)
+	for _, k := range keys {
+		t.Insert(k, k)
+	}
+	if len(ops) != 0 {
+		failf(T, t, "reported %d operations with disabled checks", len(ops))
+	}
+	t.SetSlowOpHook(0, t.Height(), func(op SlowOp[int]) {
+		ops = append(ops, op)
+	})
+	t.Append(0, 0)
+	t.Delete(0)
+	if len(ops) != 0 {
+		failf(T, t, "reported operations without restructuring: %+v", ops)
+	}
+	from, to := 10, 20
+	if t.Range(&from, &to); len(ops) != 1 {
+		failf(T, t, "range over several leafs is not reported: %+v", ops)
+	}
+	if op := ops[0]; op.Op != "range" || *op.From != from || *op.To != to || op.Nodes <= t.Height() {
+		failf(T, t, "invalid range report: %+v", op)
+	}
+	ops = ops[:0]
+	for _, k := range keys {
+		t.Delete(k)
+	}
+	if len(ops) == 0 {
+		fail(T, t, "deletes with merges are not reported")
+	}
+	for _, op := range ops {
+		if op.Op != "delete" || op.From != op.To || op.Nodes <= 0 {
+			failf(T, t, "invalid delete report: %+v", op)
+		}
+	}
+	ops = ops[:0]
+	t.SetSlowOpHook(time.Nanosecond, 0, func(op SlowOp[int]) {
+		ops = append(ops, op)
+	})
+	t.Insert(1, 1)
+	t.ValuesInRange(nil, nil)
+	if len(ops) != 2 || ops[0].Op != "insert" || *ops[0].From != 1 || ops[1].Op != "values" || ops[1].From != nil {
+		failf(T, t, "invalid reports with duration threshold: %+v", ops)
+	}
+	t.SetSlowOpHook(time.Nanosecond, 0, nil)
+	t.Insert(2, 2)
+	if len(ops) != 2 {
+		fail(T, t, "hook is not removed")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SortKeyTree is a tree ordering keys by sort keys derived from them, while keeping the keys themselves.
+// Keys with equal sort keys are treated as the same key, and operations return the key form given
+// at insertion. It suits case-insensitive and locale-aware string indexes.
+type SortKeyTree[K, S Key, V any] struct {
+	t       *BPTree[S, KeyValue[K, V]]
+	sortKey func(K) S
+}
+
+// NewSortKeyTree returns a new SortKeyTree which orders keys by sortKey. Order has the same meaning
+// as for NewBPTree. sortKey must be deterministic.
+func NewSortKeyTree[K, S Key, V any](order int, sortKey func(K) S) *SortKeyTree[K, S, V] {
+	return &SortKeyTree[K, S, V]{t: NewBPTree[S, KeyValue[K, V]](order), sortKey: sortKey}
+}
+
+// NewCaseInsensitiveTree returns a new SortKeyTree with string keys compared by FoldCase.
+func NewCaseInsensitiveTree[V any](order int) *SortKeyTree[string, string, V] {
+	return NewSortKeyTree[string, string, V](order, FoldCase)
+}
+
+// NewCollatedTree returns a new SortKeyTree with string keys ordered by binary sort keys produced by collate,
+// so that iteration order follows a locale. Strings with equal sort keys are equal keys. With a Collator c
+// of golang.org/x/text/collate it may be used like:
+//
+//	var buf collate.Buffer
+//	t := NewCollatedTree[V](0, func(s string) []byte {
+//		buf.Reset()
+//		return c.KeyFromString(&buf, s)
+//	})
+//
+// The returned slice is copied, so collate may reuse its memory on the next call.
+func NewCollatedTree[V any](order int, collate func(s string) []byte) *SortKeyTree[string, string, V] {
+	return NewSortKeyTree[string, string, V](order, func(s string) string {
+		return string(collate(s))
+	})
+}
+
+// FoldCase maps each rune of s to the smallest rune it is equivalent to under Unicode simple case folding,
+// so FoldCase(a) == FoldCase(b) if and only if strings.EqualFold(a, b).
+func FoldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return min
+	}, s)
+}
+
+// Size returns a number of key-value pairs in tree.
+func (t *SortKeyTree[K, S, V]) Size() int {
+	return t.t.Size()
+}
+
+// Insert puts a key-value pair to the tree, replacing the value and the key form if an equal key is present.
+func (t *SortKeyTree[K, S, V]) Insert(key K, val V) {
+	t.t.Insert(t.sortKey(key), KeyValue[K, V]{Key: key, Value: val})
+}
+
+// Append puts a key-value pair to the tree, appending it to the values of an equal key if it is present.
+// Each value keeps the key form it was appended with.
+func (t *SortKeyTree[K, S, V]) Append(key K, val V) {
+	t.t.Append(t.sortKey(key), KeyValue[K, V]{Key: key, Value: val})
+}
+
+// Find returns (stored key, value, true) for a key equal to a given one, or (zero, zero, false) if not found.
+func (t *SortKeyTree[K, S, V]) Find(key K) (K, V, bool) {
+	kv, ok := t.t.Find(t.sortKey(key))
+	return unwrapSortKeyValue(kv, ok)
+}
+
+// FindAll returns ([]pair, true) with stored keys and values for a key equal to a given one, or (nil, false).
+func (t *SortKeyTree[K, S, V]) FindAll(key K) ([]KeyValue[K, V], bool) {
+	return t.t.FindAll(t.sortKey(key))
+}
+
+// Delete removes a value of a key equal to a given one like BPTree.Delete, and returns (stored key, value, true),
+// or (zero, zero, false) if not found.
+func (t *SortKeyTree[K, S, V]) Delete(key K) (K, V, bool) {
+	kv, ok := t.t.Delete(t.sortKey(key))
+	return unwrapSortKeyValue(kv, ok)
+}
+
+// DeleteAll removes all values of a key equal to a given one and returns them, or (nil, false) if not found.
+func (t *SortKeyTree[K, S, V]) DeleteAll(key K) ([]KeyValue[K, V], bool) {
+	return t.t.DeleteAll(t.sortKey(key))
+}
+
+// Range returns a slice of stored key-value pairs with keys from interval [*from; *to) by sort order.
+// Nil bounds have the same meaning as for BPTree.Range.
+func (t *SortKeyTree[K, S, V]) Range(from *K, to *K) []KeyValue[K, V] {
+	var sfrom, sto *S
+	if from != nil {
+		s := t.sortKey(*from)
+		sfrom = &s
+	}
+	if to != nil {
+		s := t.sortKey(*to)
+		sto = &s
+	}
+	var result []KeyValue[K, V]
+	i := t.t.NewIterator(sfrom, sto)
+	for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+		result = append(result, kv.Value.(KeyValue[K, V]))
+	}
+	return result
+}
+
+// Entries returns a slice of all stored key-value pairs in sort order.
+func (t *SortKeyTree[K, S, V]) Entries() []KeyValue[K, V] {
+	return t.Range(nil, nil)
+}
+
+func unwrapSortKeyValue[K Key, V any](kv KeyValue[K, V], ok bool) (key K, val V, _ bool) {
+	if !ok {
+		return key, val, false
+	}
+	return kv.Key, kv.Value.(V), true
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFoldCase(T *testing.T) {
+	words := []string{"", "go", "Go", "GO", "gO", "straße", "STRASSE", "ǅ", "ǆ", "Ǆ", "K", "k", "K", "Σ", "σ", "ς"}
+	for _, a := range words {
+		for _, b := range words {
+			if (FoldCase(a) == FoldCase(b)) != strings.EqualFold(a, b) {
+				T.Errorf("FoldCase(%q) == FoldCase(%q) is %v, EqualFold is %v", a, b, !strings.EqualFold(a, b), strings.EqualFold(a, b))
+			}
+		}
+	}
+}
+
+func TestCaseInsensitiveTree(T *testing.T) {
+	t := NewCaseInsensitiveTree[int](bmax)
+	for i, k := range []string{"Bob", "alice", "CAROL", "dave"} {
+		t.Insert(k, i)
+	}
+	if k, v, ok := t.Find("BOB"); !ok || k != "Bob" || v != 0 {
+		T.Fatalf("find BOB: (%q, %d, %v)", k, v, ok)
+	}
+	t.Insert("bob", 4)
+	if t.Size() != 4 {
+		T.Fatalf("size after replacing bob: %d", t.Size())
+	}
+	if k, v, ok := t.Find("Bob"); !ok || k != "bob" || v != 4 {
+		T.Fatalf("find Bob after replace: (%q, %d, %v)", k, v, ok)
+	}
+	t.Append("Alice", 5)
+	if kvs, ok := t.FindAll("ALICE"); !ok || len(kvs) != 2 || kvs[0].Key != "alice" || kvs[1].Key != "Alice" {
+		T.Fatalf("find all ALICE: %v", kvs)
+	}
+	var keys []string
+	for _, kv := range t.Entries() {
+		keys = append(keys, kv.Key)
+	}
+	if strings.Join(keys, ",") != "alice,Alice,bob,CAROL,dave" {
+		T.Fatalf("entries: %v", keys)
+	}
+	from, to := "B", "D"
+	if kvs := t.Range(&from, &to); len(kvs) != 2 || kvs[0].Key != "bob" || kvs[1].Key != "CAROL" {
+		T.Fatalf("range [B; D): %v", kvs)
+	}
+	if k, v, ok := t.Delete("ALICE"); !ok || k != "Alice" || v != 5 {
+		T.Fatalf("delete ALICE: (%q, %d, %v)", k, v, ok)
+	}
+	if kvs, ok := t.DeleteAll("Carol"); !ok || len(kvs) != 1 || kvs[0].Key != "CAROL" {
+		T.Fatalf("delete all Carol: %v", kvs)
+	}
+	if _, _, ok := t.Find("carol"); ok || t.Size() != 3 {
+		T.Fatalf("carol found after delete, size %d", t.Size())
+	}
+}
+
+func TestCollatedTree(T *testing.T) {
+	// a toy collation ordering accented letters next to their base letters, with accents as a tie-breaker
+	base := strings.NewReplacer("ä", "a", "ö", "o", "ü", "u")
+	var buf []byte
+	t := NewCollatedTree[int](bmax, func(s string) []byte {
+		buf = append(buf[:0], base.Replace(s)...)
+		buf = append(buf, 0)
+		return append(buf, s...)
+	})
+	words := []string{"zebra", "über", "apfel", "öl", "ober", "ärger", "uhr"}
+	for i, w := range words {
+		t.Insert(w, i)
+	}
+	var keys []string
+	for _, kv := range t.Entries() {
+		keys = append(keys, kv.Key)
+	}
+	if strings.Join(keys, ",") != "apfel,ärger,ober,öl,über,uhr,zebra" {
+		T.Fatalf("entries: %v", keys)
+	}
+	if k, v, ok := t.Find("über"); !ok || k != "über" || v != 1 {
+		T.Fatalf("find über: (%q, %d, %v)", k, v, ok)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// SparseIndex returns keys of every N-th key-value pair of tree in ascending order, starting from the first
+// one, without repeating keys of multiple values. Keys split tree into intervals of about every pairs each,
+// so they may serve as a partition table for routing range queries. Pairs are found with subtree counts,
+// skipping whole subtrees, in O(n/every*log n) time. Returns nil if every is less than 1.
+func (t *BPTree[K, V]) SparseIndex(every int) []K {
+	if every < 1 {
+		return nil
+	}
+	var keys []K
+	for i := 0; i < t.size; i += every {
+		kv, _ := t.Select(i)
+		if len(keys) == 0 || cmp.Compare(keys[len(keys)-1], kv.Key) != 0 {
+			keys = append(keys, kv.Key)
+		}
+	}
+	return keys
+}
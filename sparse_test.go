@@ -0,0 +1,46 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestSparseIndex(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+	t.SetLazyDeletion(true)
+	t.DeleteAll(t.Entries()[0].Key)
+	entries := t.Entries()
+	for _, every := range []int{1, 2, 7, len(entries), len(entries) + 1} {
+		var keys []int
+		for i := 0; i < len(entries); i += every {
+			if len(keys) == 0 || keys[len(keys)-1] != entries[i].Key {
+				keys = append(keys, entries[i].Key)
+			}
+		}
+		index := t.SparseIndex(every)
+		if len(index) != len(keys) {
+			failf(T, t, "every %d: %d keys, needed %d", every, len(index), len(keys))
+		}
+		for i, k := range index {
+			if k != keys[i] {
+				failf(T, t, "every %d: key %d is %d, needed %d", every, i, k, keys[i])
+			}
+		}
+	}
+	if t.SparseIndex(0) != nil {
+		fail(T, t, "index for every 0")
+	}
+}
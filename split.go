@@ -0,0 +1,105 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+)
+
+// Split divides pairs remaining in the iterator into two iterators over adjacent intervals, so a large scan
+// can be consumed in parallel (as long as the tree is not modified). The split key is chosen at the highest
+// tree level where the remaining interval spans several nodes, so both parts get about the same number
+// of pairs. Returns (nil, nil, false) if there are not enough distinct keys left to split.
+// The iterator itself is not changed.
+func (i *RangeIterator[K, V]) Split() (*RangeIterator[K, V], *RangeIterator[K, V], bool) {
+	lo, ok := i.position()
+	if !ok {
+		return nil, nil, false
+	}
+	if i.from != nil && cmp.Less(lo, *i.from) {
+		lo = *i.from
+	}
+	mid, ok := i.t.splitKey(lo, i.to)
+	if !ok {
+		return nil, nil, false
+	}
+	first := *i
+	first.to = &mid
+	second := i.t.NewIterator(&mid, i.to)
+	return &first, second, true
+}
+
+// position returns the key the iterator is at, or false if it is exhausted.
+func (i *RangeIterator[K, V]) position() (K, bool) {
+	if i.c != nil && i.ci < len(i.c) {
+		return i.ckey, true
+	}
+	for n, j := i.n, i.i; n != nil; n, j = n.right, 0 {
+		if j < len(n.keys) {
+			return n.keys[j], true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// splitKey returns a separator key from interval (lo; *to) in the highest node on the path of lo which
+// has any, or false if there are none down to the leaf. Separators of internal nodes are chosen by subtree
+// sizes, so both parts get about the same number of pairs.
+func (t *BPTree[K, V]) splitKey(lo K, to *K) (K, bool) {
+	n := t.root
+	for {
+		a := 0
+		for a < len(n.keys) && !cmp.Less(lo, n.keys[a]) {
+			a++
+		}
+		b := a
+		for b < len(n.keys) && (to == nil || cmp.Less(n.keys[b], *to)) {
+			b++
+		}
+		if a < b && n.isLeaf() {
+			return n.keys[a+(b-a)/2], true
+		}
+		if n.isLeaf() {
+			var zero K
+			return zero, false
+		}
+		if a < b {
+			// choose the separator dividing pairs of subtrees children[a..b] most evenly
+			total := 0
+			for _, c := range n.children[a : b+1] {
+				total += c.count
+			}
+			best, left := a, n.children[a].count
+			for k := a + 1; k < b; k++ {
+				next := left + n.children[k].count
+				if abs(2*next-total) < abs(2*left-total) {
+					best, left = k, next
+				} else {
+					break
+				}
+			}
+			return n.keys[best], true
+		}
+		n = n.children[a]
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+// splitAll splits an iterator recursively down to parts which can not be split, and returns
+// pairs of all parts in order and the number of parts.
+func splitAll[K Key, V any](i *RangeIterator[K, V]) ([]KeyValue[K, V], int) {
+	first, second, ok := i.Split()
+	if !ok {
+		var items []KeyValue[K, V]
+		for kv, ok := i.Next(); ok; kv, ok = i.Next() {
+			items = append(items, kv)
+		}
+		return items, 1
+	}
+	items1, n1 := splitAll(first)
+	items2, n2 := splitAll(second)
+	return append(items1, items2...), n1 + n2
+}
+
+func TestIteratorSplit(T *testing.T) {
+	b, n, ne := bmax, numRangeTestKeys, numExtraKeys
+	_, values := makeAppendKeysValues(n)
+	keys, extraKeys := genExtraKeys(n, ne)
+	_, _, t, _ := makeTreeAppendWithKeysValues(T, b, keys, values)
+	for _, from := range extraKeys {
+		for _, to := range extraKeys {
+			needed := t.Range(from, to)
+			for skip := 0; skip <= 3 && skip <= len(needed); skip++ {
+				i := t.NewIterator(from, to)
+				for j := 0; j < skip; j++ {
+					i.Next()
+				}
+				items, _ := splitAll(i)
+				compareItems(T, t, "Split", items, needed[skip:])
+			}
+		}
+	}
+	// keys are appended in a fixed pseudo-random order, so the tree shape and thus the split are deterministic
+	keys, values = make([]int, numKeys), make([]int, numKeys)
+	for i := range keys {
+		keys[i], values[i] = i*7919%numKeys, i
+	}
+	_, _, t, _ = makeTreeAppendWithKeysValues(T, MinOrder, keys, values)
+	first, second, ok := t.NewIterator(nil, nil).Split()
+	if !ok {
+		fail(T, t, "tree is not split")
+	}
+	items1, _ := splitAll(first)
+	items2, _ := splitAll(second)
+	if len(items1) < t.Size()/4 || len(items2) < t.Size()/4 {
+		failf(T, t, "uneven split: %d and %d of %d", len(items1), len(items2), t.Size())
+	}
+	if _, parts := splitAll(t.NewIterator(nil, nil)); parts < t.Stats().Keys/2 {
+		failf(T, t, "only %d parts of %d keys", parts, t.Stats().Keys)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+)
+
+// Stats describes the structure of a tree. JSON field names are stable and safe to depend on.
+type Stats struct {
+	Size          int     `json:"size"`           // number of key-value pairs
+	Keys          int     `json:"keys"`           // number of distinct keys
+	Tombstones    int     `json:"tombstones"`     // number of tombstones left by lazy deletion
+	Height        int     `json:"height"`         // number of levels, 1 for a tree consisting of a single leaf
+	InternalNodes int     `json:"internal_nodes"` // number of internal nodes
+	LeafNodes     int     `json:"leaf_nodes"`     // number of leaf nodes
+	FillFactor    float64 `json:"fill_factor"`    // ratio of used key slots to all key slots in leaf nodes
+}
+
+// Counters holds cumulative numbers of operations performed on a tree since its creation.
+// Like with Stats, JSON field names are stable.
+type Counters struct {
+	Inserts uint64 `json:"inserts"` // key-value pairs added by Insert and Append
+	Deletes uint64 `json:"deletes"` // key-value pairs removed
+	Splits  uint64 `json:"splits"`  // node splits
+	Merges  uint64 `json:"merges"`  // node merges
+	Borrows uint64 `json:"borrows"` // keys moved between sibling nodes to balance them
+
+	// Allocations made by inserts: nodes by splits, value slices by appending values to existing keys.
+	NodeAllocs       uint64 `json:"node_allocs"`       // nodes allocated by splits, including new roots
+	NodeReuses       uint64 `json:"node_reuses"`       // nodes reused from those removed by merges instead of allocation
+	CollisionAllocs  uint64 `json:"collision_allocs"`  // value slices allocated when a key gets a second value
+	CollisionGrowths uint64 `json:"collision_growths"` // value slices reallocated to fit an appended value
+}
+
+// Height returns a number of levels in tree, 1 for a tree consisting of a single leaf.
+func (t *BPTree[K, V]) Height() int {
+	h := 1
+	for n := t.root; n.isInternal(); n = n.children[0] {
+		h++
+	}
+	return h
+}
+
+// Stats walks over all nodes of a tree and returns its structural statistics.
+func (t *BPTree[K, V]) Stats() (s Stats) {
+	t.withLabels("stats", func() {
+		s = t.stats()
+	})
+	return s
+}
+
+func (t *BPTree[K, V]) stats() Stats {
+	s := Stats{Size: t.size, Height: t.Height()}
+	var slots int
+	var visit func(n *node[K, V])
+	visit = func(n *node[K, V]) {
+		if n.isLeaf() {
+			s.LeafNodes++
+			s.Keys += len(n.keys)
+			slots += cap(n.keys)
+			return
+		}
+		s.InternalNodes++
+		for _, c := range n.children {
+			visit(c)
+		}
+	}
+	visit(t.root)
+	s.Tombstones = t.tombstones
+	s.Keys -= t.tombstones
+	if slots != 0 {
+		s.FillFactor = float64(s.Keys+s.Tombstones) / float64(slots)
+	}
+	return s
+}
+
+// WalkNodes calls fn for each node of a tree in depth-first order, starting from the root at depth 0.
+// numKeys and capKeys are the used and total numbers of key slots in the node; leaf keys include tombstones.
+// Walk stops if fn returns false.
+func (t *BPTree[K, V]) WalkNodes(fn func(depth int, isLeaf bool, numKeys, capKeys int) bool) {
+	t.walkNodes(t.root, 0, fn)
+}
+
+func (t *BPTree[K, V]) walkNodes(n *node[K, V], depth int, fn func(depth int, isLeaf bool, numKeys, capKeys int) bool) bool {
+	if !fn(depth, n.isLeaf(), len(n.keys), cap(n.keys)) {
+		return false
+	}
+	for _, c := range n.children {
+		if !t.walkNodes(c, depth+1, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Counters returns operation counters of a tree.
+func (t *BPTree[K, V]) Counters() Counters {
+	return t.counters
+}
+
+// PublishExpvar publishes tree stats and counters as an expvar variable with a given name, so they are
+// served at /debug/vars. Tree is read each time the variable is requested; since tree is not thread-safe,
+// locker (if not nil) is held while reading, and it must be the lock guarding tree modifications
+// (e.g. RLocker of a sync.RWMutex). Like expvar.Publish, panics if the name is already registered.
+func (t *BPTree[K, V]) PublishExpvar(name string, locker sync.Locker) {
+	expvar.Publish(name, expvar.Func(func() any {
+		if locker != nil {
+			locker.Lock()
+			defer locker.Unlock()
+		}
+		return t.statsReport()
+	}))
+}
+
+// StatsJSON returns tree stats and counters encoded as a single flat JSON object,
+// in the same form PublishExpvar serves them.
+func (t *BPTree[K, V]) StatsJSON() ([]byte, error) {
+	return json.Marshal(t.statsReport())
+}
+
+func (t *BPTree[K, V]) statsReport() any {
+	return struct {
+		Stats
+		Counters
+	}{t.Stats(), t.Counters()}
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+)
+
+func TestStats(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	keys := genKeys(numKeys)
+	for _, k := range keys {
+		t.Insert(k, valueForKey(k))
+	}
+	s := t.Stats()
+	if s.Size != numKeys || s.Keys != numKeys {
+		failf(T, t, "invalid size/keys: %d/%d, must be %d", s.Size, s.Keys, numKeys)
+	}
+	if s.Height < 2 || s.Height != t.Height() {
+		failf(T, t, "invalid height: %d", s.Height)
+	}
+	if s.FillFactor <= 0 || s.FillFactor > 1 {
+		failf(T, t, "invalid fill factor: %f", s.FillFactor)
+	}
+	c := t.Counters()
+	if c.Inserts != numKeys {
+		failf(T, t, "invalid inserts counter: %d, must be %d", c.Inserts, numKeys)
+	}
+	if int(c.Splits) != s.InternalNodes+s.LeafNodes-s.Height {
+		failf(T, t, "invalid splits counter: %d, stats: %+v", c.Splits, s)
+	}
+	for _, k := range keys {
+		t.Delete(k)
+	}
+	c = t.Counters()
+	if c.Deletes != numKeys || c.Merges == 0 {
+		failf(T, t, "invalid counters after delete: %+v", c)
+	}
+	if s := t.Stats(); s.Height != 1 || s.LeafNodes != 1 || s.InternalNodes != 0 || s.FillFactor != 0 {
+		failf(T, t, "invalid stats of empty tree: %+v", s)
+	}
+}
+
+func TestPublishExpvar(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	for _, k := range genKeys(numKeys) {
+		t.Insert(k, valueForKey(k))
+	}
+	var mu sync.Mutex
+	t.PublishExpvar("TestPublishExpvar", &mu)
+	var vars struct {
+		Stats
+		Counters
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("TestPublishExpvar").String()), &vars); err != nil {
+		T.Fatal(err)
+	}
+	if vars.Stats != t.Stats() || vars.Counters != t.Counters() {
+		failf(T, t, "published %+v, needed %+v, %+v", vars, t.Stats(), t.Counters())
+	}
+}
+
+func TestStatsJSON(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	for _, k := range genKeys(numKeys) {
+		t.Insert(k, valueForKey(k))
+	}
+	data, err := t.StatsJSON()
+	if err != nil {
+		T.Fatal(err)
+	}
+	var m map[string]float64
+	if err := json.Unmarshal(data, &m); err != nil {
+		T.Fatal(err)
+	}
+	s, c := t.Stats(), t.Counters()
+	for name, v := range map[string]float64{
+		"size":              float64(s.Size),
+		"keys":              float64(s.Keys),
+		"tombstones":        float64(s.Tombstones),
+		"height":            float64(s.Height),
+		"internal_nodes":    float64(s.InternalNodes),
+		"leaf_nodes":        float64(s.LeafNodes),
+		"fill_factor":       s.FillFactor,
+		"inserts":           float64(c.Inserts),
+		"deletes":           float64(c.Deletes),
+		"splits":            float64(c.Splits),
+		"merges":            float64(c.Merges),
+		"borrows":           float64(c.Borrows),
+		"node_allocs":       float64(c.NodeAllocs),
+		"node_reuses":       float64(c.NodeReuses),
+		"collision_allocs":  float64(c.CollisionAllocs),
+		"collision_growths": float64(c.CollisionGrowths),
+	} {
+		if got, ok := m[name]; !ok || got != v {
+			failf(T, t, "field %q: %v (present %v), needed %v", name, got, ok, v)
+		}
+	}
+	if len(m) != 16 {
+		failf(T, t, "unexpected fields in %s", data)
+	}
+}
+
+func TestWalkNodes(T *testing.T) {
+	t := NewBPTree[int, string](bmax)
+	t.WalkNodes(func(depth int, isLeaf bool, numKeys, capKeys int) bool {
+		if depth != 0 || !isLeaf || numKeys != 0 || capKeys != bmax {
+			failf(T, t, "invalid empty root: %d %v %d %d", depth, isLeaf, numKeys, capKeys)
+		}
+		return true
+	})
+	for _, k := range genKeys(numKeys) {
+		t.Insert(k, valueForKey(k))
+	}
+	s := t.Stats()
+	var keys, leafs, internals int
+	t.WalkNodes(func(depth int, isLeaf bool, numKeys, capKeys int) bool {
+		if numKeys > capKeys {
+			failf(T, t, "node uses %d of %d key slots", numKeys, capKeys)
+		}
+		if isLeaf {
+			if depth != s.Height-1 {
+				failf(T, t, "leaf at depth %d, height %d", depth, s.Height)
+			}
+			leafs++
+			keys += numKeys
+		} else {
+			internals++
+		}
+		return true
+	})
+	if keys != s.Keys || leafs != s.LeafNodes || internals != s.InternalNodes {
+		failf(T, t, "walked %d keys, %d leafs, %d internal nodes, stats: %+v", keys, leafs, internals, s)
+	}
+	var visited int
+	t.WalkNodes(func(int, bool, int, int) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		failf(T, t, "walk did not stop: %d nodes visited", visited)
+	}
+}
+
+func TestAllocCounters(T *testing.T) {
+	t := NewBPTree[int, int](bmax)
+	for _, k := range genKeys(numKeys) {
+		t.Insert(k, k)
+	}
+	s, c := t.Stats(), t.Counters()
+	if int(c.NodeAllocs) != s.InternalNodes+s.LeafNodes-1 || c.CollisionAllocs != 0 || c.CollisionGrowths != 0 {
+		failf(T, t, "invalid counters after inserts: %+v, stats: %+v", c, s)
+	}
+	for v := 0; v < 8; v++ {
+		t.Append(0, v)
+	}
+	c2 := t.Counters()
+	if c2.NodeAllocs != c.NodeAllocs || c2.CollisionAllocs != 1 || c2.CollisionGrowths == 0 || c2.CollisionGrowths >= 7 {
+		failf(T, t, "invalid counters after appends: %+v", c2)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+)
+
+// iteratorToken is the content of a token returned by RangeIterator.Token.
+type iteratorToken[K Key] struct {
+	From, To *K
+	Key      K   // key of the next pair
+	Skip     int // values of Key already returned
+	Done     bool
+}
+
+// Token returns the position of the iterator and its interval as an opaque token, encoded with
+// encoding/gob, so keys must be encodable by gob. The token does not refer to tree memory: iteration
+// may be resumed with ResumeIterator on another tree with the same content, e.g. after a restart
+// with the tree restored by ImportStream. If the tree has been modified, the resumed iterator continues
+// from the first pair after the position by key order.
+func (i *RangeIterator[K, V]) Token() ([]byte, error) {
+	tok := iteratorToken[K]{From: i.from, To: i.to}
+	if i.c != nil && i.ci < len(i.c) {
+		tok.Key, tok.Skip = i.ckey, i.ci
+	} else if k, ok := i.position(); ok {
+		tok.Key = k
+	} else {
+		tok.Done = true
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(tok); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ResumeIterator returns an iterator positioned where the iterator a given token was taken from was.
+func (t *BPTree[K, V]) ResumeIterator(token []byte) (*RangeIterator[K, V], error) {
+	var tok iteratorToken[K]
+	if err := gob.NewDecoder(bytes.NewReader(token)).Decode(&tok); err != nil {
+		return nil, err
+	}
+	i := t.NewIterator(tok.From, tok.To)
+	if tok.Done {
+		i.n = nil
+		return i, nil
+	}
+	if tok.From == nil || cmp.Less(*tok.From, tok.Key) {
+		i.Reset(&tok.Key, tok.To)
+	}
+	if tok.Skip == 0 {
+		return i, nil
+	}
+	kv, ok := i.Next()
+	switch {
+	case !ok:
+	case cmp.Compare(kv.Key, tok.Key) != 0:
+		// the key is gone, so the pair just read is the next one
+		i.Reset(&kv.Key, tok.To)
+	case i.c != nil:
+		i.ci = min(tok.Skip, len(i.c))
+	}
+	return i, nil
+}
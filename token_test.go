@@ -0,0 +1,65 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestIteratorToken(T *testing.T) {
+	_, _, t, _ := makeTreeAppend(T, bmax, numRangeTestKeys)
+	t2 := NewBPTree[int, int](MinOrder)
+	for _, kv := range t.Entries() {
+		t2.Append(kv.Key, kv.Value.(int))
+	}
+	from, to := t.Entries()[3].Key, t.Entries()[t.Size()-3].Key
+	needed := t.Range(&from, &to)
+	for read := 0; read <= len(needed); read++ {
+		i := t.NewIterator(&from, &to)
+		for j := 0; j < read; j++ {
+			i.Next()
+		}
+		token, err := i.Token()
+		if err != nil {
+			T.Fatal(err)
+		}
+		resumed, err := t2.ResumeIterator(token)
+		if err != nil {
+			T.Fatal(err)
+		}
+		var items []KeyValue[int, int]
+		for kv, ok := resumed.Next(); ok; kv, ok = resumed.Next() {
+			items = append(items, kv)
+		}
+		compareItems(T, t2, "ResumeIterator", items, needed[read:])
+	}
+	if _, err := t.ResumeIterator([]byte("garbage")); err == nil {
+		fail(T, t, "garbage token accepted")
+	}
+	var i *RangeIterator[int, int]
+	var kv KeyValue[int, int]
+	for i = t.NewIterator(nil, nil); ; {
+		kv, _ = i.Next()
+		if vals, _ := t.FindAll(kv.Key); len(vals) > 1 {
+			break
+		}
+	}
+	token, _ := i.Token()
+	t.DeleteAll(kv.Key)
+	resumed, _ := t.ResumeIterator(token)
+	if next, ok := resumed.Next(); !ok || next.Key <= kv.Key {
+		failf(T, t, "resumed after deleted key %d at (%v, %v)", kv.Key, next, ok)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"time"
+)
+
+// SetRange replaces every value of keys from interval [*from; *to) with the result of fn called for it,
+// in one pass over leaves, and returns the number of replaced values. Nil given as a parameter will
+// be interpreted as begin or end whole tree key diapason. Multiple values of a key are passed one by one.
+// Tree must not be modified by fn.
+func (t *BPTree[K, V]) SetRange(from *K, to *K, fn func(key K, val V) V) int {
+	var count int
+	i := RangeIterator[K, V]{t: t}
+	i.Reset(from, to)
+	for n := i.n; n != nil; n = n.right {
+		for j, key := range n.keys {
+			if from != nil && cmp.Less(key, *from) {
+				continue
+			}
+			if to != nil && !cmp.Less(key, *to) {
+				return t.updated(count)
+			}
+			switch c := n.values[j].(type) {
+			case tombstone:
+			case collision[V]:
+				for idx, val := range c {
+					c[idx] = t.replaced(key, idx, val, fn(key, val))
+				}
+				count += len(c)
+			default:
+				n.values[j] = t.replaced(key, 0, c.(V), fn(key, c.(V)))
+				count++
+			}
+		}
+	}
+	return t.updated(count)
+}
+
+// Upsert sets the value of a given key to the result of fn and returns it. Fn is passed the current value
+// of key, as returned by Find, and whether key is present. Like Insert, the result replaces all values
+// of key. A key with a single value is updated in place after one descent to its leaf. Tree must not
+// be modified by fn.
+func (t *BPTree[K, V]) Upsert(key K, fn func(old V, exists bool) V) V {
+	n := t.seekLeaf(key)
+	if i, found := n.search(key); found {
+		switch v := n.values[i].(type) {
+		case tombstone:
+		case collision[V]:
+			val := fn(v[0], true)
+			t.insert(key, val, true)
+			return val
+		default:
+			val := t.replaced(key, 0, v.(V), fn(v.(V), true))
+			n.values[i] = val
+			t.version++
+			return val
+		}
+	}
+	var zero V
+	val := fn(zero, false)
+	t.insert(key, val, true)
+	return val
+}
+
+// GetOrInsert returns (value, true) if a given key is present, where value is the one returned by Find,
+// or inserts val for key and returns (val, false) otherwise. Either way tree is descended only once.
+func (t *BPTree[K, V]) GetOrInsert(key K, val V) (V, bool) {
+	// nodes on the way to the leaf, whose counts and splits are updated after insertion
+	path := make([]*node[K, V], 0, 16)
+	n := t.root
+	for n.isInternal() {
+		path = append(path, n)
+		n = n.children[n.childIndex(key)]
+	}
+	if i, found := n.search(key); found {
+		switch v := n.values[i].(type) {
+		case tombstone:
+		case collision[V]:
+			return v[0], true
+		default:
+			return v.(V), true
+		}
+	}
+	if t.recorder != nil {
+		t.recorder.record(logRecord[K, V]{Op: recordInsert, Key: key, Value: val})
+	}
+	if t.memCostFn != nil {
+		t.memCost += t.memCostFn(key, val)
+	}
+	if t.slowOps != nil {
+		defer t.observePoint("insert", key, time.Now(), t.restructures())
+	}
+	added, key2, n2 := n.insertToLeaf(t, key, val, true)
+	for j := len(path) - 1; j >= 0; j-- {
+		path[j].count += added
+		if n2 != nil {
+			key2, n2 = path[j].insertToInternal(t, key2, n2)
+		}
+	}
+	if n2 != nil {
+		t.growRoot(key2, n2)
+	}
+	t.version++
+	t.size += added
+	t.counters.Inserts++
+	return val, false
+}
+
+// replaced accounts replacement of old value of a key at index idx with val, and returns val.
+func (t *BPTree[K, V]) replaced(key K, idx int, old, val V) V {
+	if t.recorder != nil {
+		t.recorder.record(logRecord[K, V]{Op: recordReplaceOne, Key: key, Value: val, Idx: idx})
+	}
+	if t.memCostFn != nil {
+		t.memCost += t.memCostFn(key, val) - t.memCostFn(key, old)
+	}
+	return val
+}
+
+func (t *BPTree[K, V]) updated(count int) int {
+	if count > 0 {
+		t.version++
+	}
+	return count
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetRange(T *testing.T) {
+	keys, extra := genExtraKeys(numRangeTestKeys, numExtraKeys)
+	for _, from := range extra {
+		for _, to := range extra {
+			t := NewBPTree[int, int](MinOrder)
+			for _, k := range keys {
+				t.Append(k, k)
+				if k%3 == 0 {
+					t.Append(k, k+1)
+				}
+			}
+			needed := t.Range(from, to)
+			for i := range needed {
+				needed[i].Value = -needed[i].Value.(int)
+			}
+			version := t.Version()
+			if count := t.SetRange(from, to, func(_ int, v int) int { return -v }); count != len(needed) {
+				failf(T, t, "[%v; %v): %d values updated, needed %d", from, to, count, len(needed))
+			}
+			compareItems(T, t, "SetRange", t.Range(from, to), needed)
+			if (len(needed) > 0) != (t.Version() != version) {
+				failf(T, t, "[%v; %v): version changed is %t", from, to, t.Version() != version)
+			}
+		}
+	}
+}
+
+func TestSetRangeRecord(T *testing.T) {
+	keys, values, t, _ := makeTreeAppend(T, bmax, numKeys)
+	_, _, t2, _ := makeTreeAppendWithKeysValues(T, bmax, keys, values)
+	var log bytes.Buffer
+	t.Record(&log)
+	from, to := numKeys/4, numKeys/2
+	t.SetRange(&from, &to, func(k int, v int) int { return k + v })
+	t.StopRecording()
+	if err := t2.Replay(&log); err != nil {
+		T.Fatal(err)
+	}
+	compareItems(T, t2, "Replay", t2.Entries(), t.Entries())
+}
+
+func TestUpsert(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, bmax, numKeys)
+	version := t.Version()
+	for k := -numExtraKeys; k < numKeys+numExtraKeys; k++ {
+		vals, exists := m[k]
+		val := t.Upsert(k, func(old int, ok bool) int {
+			if ok != exists || ok && old != vals[0] {
+				failf(T, t, "key %d: upsert called with (%d, %t), needed (%v, %t)", k, old, ok, vals, exists)
+			}
+			return old + k
+		})
+		if len(vals) == 0 {
+			vals = []int{0}
+		}
+		if val != vals[0]+k {
+			failf(T, t, "key %d: upserted %d, needed %d", k, val, vals[0]+k)
+		}
+		m[k] = []int{val}
+	}
+	compareWithMap(T, t, m)
+	if t.Version() == version {
+		fail(T, t, "version is not changed")
+	}
+}
+
+func TestGetOrInsert(T *testing.T) {
+	_, _, t, m := makeTreeAppend(T, MinOrder, numKeys)
+	t.SetLazyDeletion(true)
+	deleted := t.Entries()[0].Key
+	t.DeleteAll(deleted)
+	delete(m, deleted)
+	for k := -numExtraKeys; k < numKeys+numExtraKeys; k++ {
+		vals, exists := m[k]
+		val, ok := t.GetOrInsert(k, -k)
+		if ok != exists {
+			failf(T, t, "key %d: found is %t, needed %t", k, ok, exists)
+		}
+		if !exists {
+			vals = []int{-k}
+			m[k] = vals
+		}
+		if val != vals[0] {
+			failf(T, t, "key %d: value %d, needed %d", k, val, vals[0])
+		}
+	}
+	compareWithMap(T, t, m)
+	if err := t.Validate(); err != nil {
+		failf(T, t, "tree validation failed: %s", err)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { t.GetOrInsert(0, 0) }); allocs != 0 {
+		failf(T, t, "get made %v allocations", allocs)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"errors"
+)
+
+// ErrInvalidUUIDKey is returned when parsing a UUID key of a length other than 16 bytes.
+var ErrInvalidUUIDKey = errors.New("invalid UUID key")
+
+// UUIDKey returns a UUID as a 16-byte string key, which compares byte-wise like the UUID itself.
+// Version 7 UUIDs start with a timestamp, so their keys are ordered by creation time.
+func UUIDKey(u [16]byte) string {
+	return string(u[:])
+}
+
+// ParseUUIDKey returns a UUID from a key produced by UUIDKey.
+func ParseUUIDKey(key string) (u [16]byte, err error) {
+	if len(key) != len(u) {
+		return u, ErrInvalidUUIDKey
+	}
+	copy(u[:], key)
+	return u, nil
+}
+
+// UUIDTimeKey is like UUIDKey, but moves timestamp fields of a version 1 UUID to most significant first
+// (time_hi_and_version, time_mid, time_low), so keys are ordered by creation time. Fields are moved for
+// any UUID, so a tree should use either UUIDKey or UUIDTimeKey for all its keys.
+func UUIDTimeKey(u [16]byte) string {
+	var k [16]byte
+	copy(k[0:2], u[6:8])
+	copy(k[2:4], u[4:6])
+	copy(k[4:8], u[0:4])
+	copy(k[8:], u[8:])
+	return string(k[:])
+}
+
+// ParseUUIDTimeKey returns a UUID from a key produced by UUIDTimeKey.
+func ParseUUIDTimeKey(key string) (u [16]byte, err error) {
+	if len(key) != len(u) {
+		return u, ErrInvalidUUIDKey
+	}
+	copy(u[6:8], key[0:2])
+	copy(u[4:6], key[2:4])
+	copy(u[0:4], key[4:8])
+	copy(u[8:], key[8:])
+	return u, nil
+}
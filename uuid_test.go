@@ -0,0 +1,62 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+// uuidV1 returns a version 1 UUID with a given 60-bit timestamp and random node.
+func uuidV1(r *rand.Rand, ts uint64) (u [16]byte) {
+	binary.BigEndian.PutUint32(u[0:4], uint32(ts))
+	binary.BigEndian.PutUint16(u[4:6], uint16(ts>>32))
+	binary.BigEndian.PutUint16(u[6:8], uint16(ts>>48)&0x0fff|0x1000)
+	r.Read(u[8:])
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+func TestUUIDKey(T *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var u [16]byte
+	r.Read(u[:])
+	if v, err := ParseUUIDKey(UUIDKey(u)); err != nil || v != u {
+		T.Fatalf("parse key: (%x, %v), needed %x", v, err, u)
+	}
+	if v, err := ParseUUIDTimeKey(UUIDTimeKey(u)); err != nil || v != u {
+		T.Fatalf("parse time key: (%x, %v), needed %x", v, err, u)
+	}
+	if _, err := ParseUUIDKey("short"); err != ErrInvalidUUIDKey {
+		T.Fatalf("parse short key: %v", err)
+	}
+	if _, err := ParseUUIDTimeKey("short"); err != ErrInvalidUUIDKey {
+		T.Fatalf("parse short time key: %v", err)
+	}
+	t := NewBPTree[string, uint64](bmax)
+	for i := 0; i < numKeys; i++ {
+		ts := r.Uint64() >> 4
+		t.Insert(UUIDTimeKey(uuidV1(r, ts)), ts)
+	}
+	var prev uint64
+	for i, kv := range t.Entries() {
+		ts := kv.Value.(uint64)
+		if i > 0 && ts < prev {
+			T.Fatalf("timestamp %d is after %d", prev, ts)
+		}
+		prev = ts
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// Validate checks structural invariants of tree and returns an error describing the first violation found,
+// or nil if tree is consistent: all leaves are at the same depth, nodes other than root are at least half
+// full, keys are ordered within nodes and fit separator keys of their parents, nodes of every level are
+// linked to their siblings, and numbers of pairs and tombstones match counters of tree. It visits every node
+// once, taking O(n) time, and is meant for integrity checks, e.g. after recovery or in fuzz tests.
+func (t *BPTree[K, V]) Validate() error {
+	maxDepth, numTombstones := -1, 0
+	var levels [][]*node[K, V] // nodes of every level from left to right, to check sibling links
+	var visitNode func(n *node[K, V], min, max *K, depth int) error
+	visitNode = func(n *node[K, V], min, max *K, depth int) error {
+		if depth == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], n)
+		if n.isLeaf() {
+			if maxDepth == -1 {
+				maxDepth = depth
+			} else if maxDepth != depth {
+				return fmt.Errorf("maxDepth(%d) != depth(%d)", maxDepth, depth)
+			}
+			if t.keysOnly != (n.values == nil) {
+				return fmt.Errorf("leaf.values is nil(%t) in a tree with keys only(%t)", n.values == nil, t.keysOnly)
+			}
+			if !t.keysOnly && len(n.keys) != len(n.values) {
+				return fmt.Errorf("len(leaf.keys)(%d) != len(leaf.values)(%d)", len(n.keys), len(n.values))
+			}
+			if depth != 0 && len(n.keys) < n.bmin {
+				return fmt.Errorf("len(leaf.keys)(%d) < bmin(%d)", len(n.keys), n.bmin)
+			}
+			if count := leafCount(n); n.count != count {
+				return fmt.Errorf("leaf.count(%d) != number of pairs(%d)", n.count, count)
+			}
+			for i := range n.keys {
+				if n.values != nil {
+					if _, ok := n.values[i].(tombstone); ok {
+						numTombstones++
+					}
+				}
+				if i > 0 && !cmp.Less(n.keys[i-1], n.keys[i]) {
+					return fmt.Errorf("leaf.key(%v) is not less than next(%v)", n.keys[i-1], n.keys[i])
+				}
+			}
+			if depth != 0 {
+				for _, k := range n.keys {
+					if min != nil && cmp.Less(k, *min) {
+						return fmt.Errorf("leaf.key(%v) < min(%v)", k, *min)
+					} else if max != nil && !cmp.Less(k, *max) {
+						return fmt.Errorf("leaf.key(%v) >= max(%v)", k, *max)
+					}
+				}
+			}
+		} else {
+			if len(n.keys) != len(n.children)-1 {
+				return fmt.Errorf("len(node.keys)(%d) != len(node.children)-1(%d)", len(n.keys), len(n.children)-1)
+			}
+			if depth != 0 && len(n.children) < n.bmin {
+				return fmt.Errorf("len(node.children)(%d) < bmin(%d)", len(n.children), n.bmin)
+			}
+			if count := childrenCount(n); n.count != count {
+				return fmt.Errorf("node.count(%d) != number of pairs in children(%d)", n.count, count)
+			}
+			for i := 1; i < len(n.keys); i++ {
+				if !cmp.Less(n.keys[i-1], n.keys[i]) {
+					return fmt.Errorf("node.key(%v) is not less than next(%v)", n.keys[i-1], n.keys[i])
+				}
+			}
+			for i, c := range n.children {
+				if i < len(n.keys) {
+					if min != nil && cmp.Less(n.keys[i], *min) {
+						return fmt.Errorf("node.key(%v) < min(%v)", n.keys[i], *min)
+					} else if max != nil && !cmp.Less(n.keys[i], *max) {
+						return fmt.Errorf("node.key(%v) >= max(%v)", n.keys[i], *max)
+					}
+				}
+				var cmin, cmax *K
+				if i == 0 {
+					cmin = min
+					if len(n.keys) == 0 {
+						cmax = max
+					} else {
+						cmax = &(n.keys[0])
+					}
+				} else if i == len(n.keys) {
+					cmin, cmax = &(n.keys[i-1]), max
+				} else {
+					cmin, cmax = &(n.keys[i-1]), &(n.keys[i])
+				}
+				if err := visitNode(c, cmin, cmax, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := visitNode(t.root, nil, nil, 0); err != nil {
+		return err
+	}
+	if t.root.count != t.size {
+		return fmt.Errorf("root.count(%d) != size(%d)", t.root.count, t.size)
+	}
+	if numTombstones != t.tombstones {
+		return fmt.Errorf("number of tombstones(%d) != tombstones(%d)", numTombstones, t.tombstones)
+	}
+	for lvl, nodes := range levels {
+		for i, n := range nodes {
+			if i == 0 && n.left != nil {
+				return fmt.Errorf("first.left != nil on level(%d)", lvl)
+			}
+			if i != 0 && n.left != nodes[i-1] {
+				return fmt.Errorf("node.left != previous on level(%d)", lvl)
+			}
+			if i == len(nodes)-1 && n.right != nil {
+				return fmt.Errorf("last.right != nil on level(%d)", lvl)
+			}
+			if i != len(nodes)-1 && n.right != nodes[i+1] {
+				return fmt.Errorf("node.right != next on level(%d)", lvl)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestValidate(T *testing.T) {
+	corruptions := map[string]func(t *BPTree[int, int]){
+		"unordered keys": func(t *BPTree[int, int]) {
+			l := t.firstLeaf()
+			l.keys[0], l.keys[1] = l.keys[1], l.keys[0]
+		},
+		"key out of parent range": func(t *BPTree[int, int]) {
+			t.firstLeaf().right.keys[0] = -1
+		},
+		"broken sibling link": func(t *BPTree[int, int]) {
+			t.firstLeaf().right = nil
+		},
+		"wrong count": func(t *BPTree[int, int]) {
+			t.root.count++
+		},
+		"wrong tombstones": func(t *BPTree[int, int]) {
+			t.tombstones++
+		},
+		"underfull leaf": func(t *BPTree[int, int]) {
+			l := t.firstLeaf()
+			l.keys, l.values = l.keys[:1], l.values[:1]
+		},
+	}
+	for name, corrupt := range corruptions {
+		_, _, t, _ := makeTreeAppend(T, bmax, numKeys)
+		if err := t.Validate(); err != nil {
+			failf(T, t, "%s: valid tree: %s", name, err)
+		}
+		corrupt(t)
+		if err := t.Validate(); err == nil {
+			failf(T, t, "%s: corrupted tree is valid", name)
+		}
+	}
+}
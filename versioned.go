@@ -0,0 +1,106 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+// VersionedTree is a tree which keeps previous values of keys tagged with sequence numbers,
+// so values can be read as of an earlier point of its history. Every key holds a single value.
+type VersionedTree[K Key, V any] struct {
+	t   *BPTree[K, []valueVersion[V]]
+	seq uint64
+}
+
+type valueVersion[V any] struct {
+	seq     uint64
+	val     V
+	deleted bool
+}
+
+// NewVersionedTree returns a new VersionedTree. Order has the same meaning as for NewBPTree.
+func NewVersionedTree[K Key, V any](order int) *VersionedTree[K, V] {
+	return &VersionedTree[K, V]{t: NewBPTree[K, []valueVersion[V]](order)}
+}
+
+// Seq returns the sequence number of the last modification, 0 if tree has not been modified.
+func (t *VersionedTree[K, V]) Seq() uint64 {
+	return t.seq
+}
+
+// Insert puts a value for a given key, keeping the previous one, and returns the sequence number of the change.
+func (t *VersionedTree[K, V]) Insert(key K, val V) uint64 {
+	return t.add(key, valueVersion[V]{val: val})
+}
+
+// Delete marks a key as deleted, keeping its previous value, and returns (sequence number of the change, true),
+// or (0, false) if the key is not present.
+func (t *VersionedTree[K, V]) Delete(key K) (uint64, bool) {
+	if _, ok := t.Find(key); !ok {
+		return 0, false
+	}
+	return t.add(key, valueVersion[V]{deleted: true}), true
+}
+
+func (t *VersionedTree[K, V]) add(key K, v valueVersion[V]) uint64 {
+	t.seq++
+	v.seq = t.seq
+	vs, _ := t.t.Find(key)
+	t.t.Insert(key, append(vs, v))
+	return t.seq
+}
+
+// Find returns (value, true) for a given key, or (zero, false) if not found.
+func (t *VersionedTree[K, V]) Find(key K) (V, bool) {
+	return t.FindAsOf(key, t.seq)
+}
+
+// FindAsOf returns (value, true) for a given key as it was right after the change with sequence number seq,
+// or (zero, false) if the key was not present then or its history is pruned.
+func (t *VersionedTree[K, V]) FindAsOf(key K, seq uint64) (val V, ok bool) {
+	vs, _ := t.t.Find(key)
+	for i := len(vs) - 1; i >= 0; i-- {
+		if vs[i].seq <= seq {
+			if vs[i].deleted {
+				return val, false
+			}
+			return vs[i].val, true
+		}
+	}
+	return val, false
+}
+
+// Prune removes versions which are not visible as of sequence number watermark or later, so FindAsOf
+// stays exact for seq >= watermark. Keys deleted as of watermark are removed from tree.
+func (t *VersionedTree[K, V]) Prune(watermark uint64) {
+	for _, kv := range t.t.Entries() {
+		vs := kv.Value.([]valueVersion[V])
+		i := len(vs) - 1
+		for i > 0 && vs[i].seq > watermark {
+			i--
+		}
+		if vs[i].seq > watermark {
+			continue
+		}
+		if vs[i].deleted {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		if i == len(vs) {
+			t.t.Delete(kv.Key)
+			continue
+		}
+		t.t.Insert(kv.Key, append([]valueVersion[V](nil), vs[i:]...))
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Dmitry Dikun
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bptree
+
+import (
+	"testing"
+)
+
+func TestVersionedTree(T *testing.T) {
+	t := NewVersionedTree[int, string](bmax)
+	s1 := t.Insert(1, "a")
+	s2 := t.Insert(1, "b")
+	s3 := t.Insert(2, "x")
+	s4, ok := t.Delete(1)
+	if !ok || s1 != 1 || s2 != 2 || s3 != 3 || s4 != 4 || t.Seq() != 4 {
+		T.Fatalf("sequence numbers: %d %d %d %d %v, seq %d", s1, s2, s3, s4, ok, t.Seq())
+	}
+	if _, ok := t.Delete(1); ok {
+		T.Fatal("deleted key deleted again")
+	}
+	s5 := t.Insert(1, "c")
+	history := []struct {
+		seq    uint64
+		v1, v2 string
+	}{{0, "", ""}, {s1, "a", ""}, {s2, "b", ""}, {s3, "b", "x"}, {s4, "", "x"}, {s5, "c", "x"}}
+	check := func(from int) {
+		for _, h := range history[from:] {
+			if v, ok := t.FindAsOf(1, h.seq); v != h.v1 || ok != (h.v1 != "") {
+				T.Fatalf("key 1 as of %d: (%q, %v), needed %q", h.seq, v, ok, h.v1)
+			}
+			if v, ok := t.FindAsOf(2, h.seq); v != h.v2 || ok != (h.v2 != "") {
+				T.Fatalf("key 2 as of %d: (%q, %v), needed %q", h.seq, v, ok, h.v2)
+			}
+		}
+	}
+	check(0)
+	if v, ok := t.Find(1); !ok || v != "c" {
+		T.Fatalf("find 1: (%q, %v)", v, ok)
+	}
+	for i, h := range history {
+		t.Prune(h.seq)
+		check(i)
+	}
+	if vs, _ := t.t.Find(1); len(vs) != 1 {
+		T.Fatalf("versions of key 1 after pruning: %v", vs)
+	}
+	t.Delete(2)
+	t.Prune(t.Seq())
+	if t.t.Size() != 1 {
+		T.Fatalf("deleted key is not pruned, size %d", t.t.Size())
+	}
+}